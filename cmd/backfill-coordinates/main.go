@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"health/services"
+	"log"
+)
+
+// backfill-coordinates is a one-off command that sets the coordinates of doctors stored without
+// them by looking up their location in a local gazetteer file.
+func main() {
+	services.LoadConfig()
+	services.InitMongoDB()
+
+	gazetteer := flag.String("gazetteer", "data/gazetteer.csv", "CSV file with name, latitude and longitude columns")
+	dryRun := flag.Bool("dry-run", false, "Report the backfill without writing to the database")
+	flag.Parse()
+
+	places, err := services.LoadGazetteer(*gazetteer)
+	if err != nil {
+		log.Fatalf("Invalid gazetteer: %v", err)
+	}
+
+	updated, unmatched, err := services.BackfillDoctorCoordinates(places, *dryRun)
+	if err != nil {
+		log.Fatalf("Coordinates backfill failed: %v", err)
+	}
+
+	if *dryRun {
+		fmt.Printf("Dry run: %d doctor(s) would be updated, %d not found in the gazetteer\n", updated, unmatched)
+		return
+	}
+	fmt.Printf("✓ Updated %d doctor(s), %d not found in the gazetteer\n", updated, unmatched)
+}
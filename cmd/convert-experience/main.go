@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"health/services"
+	"log"
+)
+
+// convert-experience is a one-off command that rewrites doctors stored with the legacy free
+// text experience field to experience_years, and backfills their search fields.
+func main() {
+	services.LoadConfig()
+	services.InitMongoDB()
+
+	dryRun := flag.Bool("dry-run", false, "Report the conversion without writing to the database")
+	flag.Parse()
+
+	converted, failed, err := services.ConvertLegacyDoctorExperience(*dryRun)
+	if err != nil {
+		log.Fatalf("Experience conversion failed: %v", err)
+	}
+
+	if *dryRun {
+		fmt.Printf("Dry run: %d doctor(s) would be converted, %d cannot be converted\n", converted, failed)
+		return
+	}
+	fmt.Printf("✓ Converted %d doctor(s), %d cannot be converted\n", converted, failed)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"health/services"
+	"log"
+	"time"
+)
+
+// convert-schedules is a one-off command that rewrites doctors stored with the legacy
+// work_days, work_time and work_time_end fields to the typed weekly schedule.
+func main() {
+	services.LoadConfig()
+	services.InitMongoDB()
+
+	timezone := flag.String("timezone", "UTC", "IANA timezone the legacy work times are local to")
+	dryRun := flag.Bool("dry-run", false, "Report the conversion without writing to the database")
+	flag.Parse()
+
+	if _, err := time.LoadLocation(*timezone); err != nil {
+		log.Fatalf("Invalid timezone %q: %v", *timezone, err)
+	}
+
+	converted, failed, err := services.ConvertLegacyDoctorSchedules(*timezone, *dryRun)
+	if err != nil {
+		log.Fatalf("Schedule conversion failed: %v", err)
+	}
+
+	if *dryRun {
+		fmt.Printf("Dry run: %d doctor(s) would be converted, %d cannot be converted\n", converted, failed)
+		return
+	}
+	fmt.Printf("✓ Converted %d doctor(s), %d cannot be converted\n", converted, failed)
+}
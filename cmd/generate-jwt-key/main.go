@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// generate-jwt-key writes a new private key for the JWT key set to JWT_KEYS_DIR. The file is named
+// after the kid of the key, so that it can be activated with JWT_ACTIVE_KID.
+func main() {
+	dir := flag.String("dir", "keys", "Directory of the JWT key set")
+	alg := flag.String("alg", "ES256", "Signing algorithm of the key, ES256 or RS256")
+	kid := flag.String("kid", time.Now().UTC().Format("20060102-150405"), "Key id, used as the file name")
+	flag.Parse()
+
+	var der []byte
+	var err error
+	switch *alg {
+	case "ES256":
+		var key *ecdsa.PrivateKey
+		if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err == nil {
+			der, err = x509.MarshalPKCS8PrivateKey(key)
+		}
+	case "RS256":
+		var key *rsa.PrivateKey
+		if key, err = rsa.GenerateKey(rand.Reader, 3072); err == nil {
+			der, err = x509.MarshalPKCS8PrivateKey(key)
+		}
+	default:
+		log.Fatalf("Unsupported algorithm %q, use ES256 or RS256", *alg)
+	}
+	if err != nil {
+		log.Fatalf("Cannot generate key: %v", err)
+	}
+
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		log.Fatalf("Cannot create %s: %v", *dir, err)
+	}
+	path := filepath.Join(*dir, *kid+".pem")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		log.Fatalf("Cannot create %s: %v", path, err)
+	}
+	defer file.Close()
+	if err := pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		log.Fatalf("Cannot write %s: %v", path, err)
+	}
+
+	fmt.Printf("✓ Wrote %s key %s, activate it with JWT_ACTIVE_KID=%s\n", *alg, path, *kid)
+}
@@ -21,7 +21,7 @@ func main() {
 		fmt.Println("Note: MongoDB is schema-less and doesn't require SQL migrations.")
 		fmt.Println("Collections are created automatically when first used.")
 		fmt.Println("\nAvailable commands:")
-		fmt.Println("  migrate  - No-op for MongoDB (collections created automatically)")
+		fmt.Println("  migrate  - Create MongoDB indexes (collections created automatically)")
 		fmt.Println("  rollback - Not applicable for MongoDB")
 		fmt.Println("  fresh    - Drop all collections and recreate")
 		fmt.Println("  status   - Show collection status")
@@ -30,7 +30,10 @@ func main() {
 
 	switch *command {
 	case "migrate":
-		fmt.Println("✓ MongoDB is schema-less - no migrations needed")
+		if err := services.CreateMongoIndexes(); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		fmt.Println("✓ MongoDB is schema-less - indexes created")
 		fmt.Println("Collections will be created automatically when first used.")
 
 	case "rollback":
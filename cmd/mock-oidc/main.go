@@ -0,0 +1,202 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const mockKeyID = "mock-oidc"
+
+// mockAuthorization is an authorization code waiting to be exchanged at the token endpoint.
+type mockAuthorization struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	email         string
+	expiresAt     time.Time
+}
+
+// mock-oidc runs a local OpenID Connect provider to try out and test the OIDC login without a real one.
+// It approves every authorization right away, for the user given by the flags or by a login_hint
+// parameter, and signs its ID tokens with an RS256 key generated at startup.
+func main() {
+	addr := flag.String("addr", ":9090", "Address to listen on")
+	issuer := flag.String("issuer", "http://localhost:9090", "Issuer of the provider, set OIDC_ISSUER to it")
+	clientID := flag.String("client-id", "health-api", "Client id the provider accepts, set OIDC_CLIENT_ID to it")
+	clientSecret := flag.String("client-secret", "", "Client secret the token endpoint requires, if set")
+	email := flag.String("email", "patient@example.com", "Email address of the user logging in, unless a login_hint is given")
+	name := flag.String("name", "Mock Patient", "Name of the user logging in")
+	unverified := flag.Bool("unverified", false, "Report the email address as not verified")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("Cannot generate key: %v", err)
+	}
+
+	var mutex sync.Mutex
+	codes := map[string]*mockAuthorization{}
+
+	http.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                                *issuer,
+			"authorization_endpoint":                *issuer + "/authorize",
+			"token_endpoint":                        *issuer + "/token",
+			"jwks_uri":                              *issuer + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+			"code_challenge_methods_supported":      []string{"S256"},
+		})
+	})
+
+	http.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": mockKeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+
+	http.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		redirectURI, err := url.Parse(query.Get("redirect_uri"))
+		if err != nil || !redirectURI.IsAbs() {
+			http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+			return
+		}
+		if query.Get("client_id") != *clientID || query.Get("response_type") != "code" {
+			http.Error(w, "unknown client_id or unsupported response_type", http.StatusBadRequest)
+			return
+		}
+		if query.Get("code_challenge_method") != "" && query.Get("code_challenge_method") != "S256" {
+			http.Error(w, "unsupported code_challenge_method", http.StatusBadRequest)
+			return
+		}
+
+		code := randomString()
+		userEmail := *email
+		if hint := query.Get("login_hint"); hint != "" {
+			userEmail = hint
+		}
+		mutex.Lock()
+		codes[code] = &mockAuthorization{
+			clientID:      query.Get("client_id"),
+			redirectURI:   redirectURI.String(),
+			nonce:         query.Get("nonce"),
+			codeChallenge: query.Get("code_challenge"),
+			email:         userEmail,
+			expiresAt:     time.Now().Add(time.Minute),
+		}
+		mutex.Unlock()
+
+		callback := redirectURI.Query()
+		callback.Set("code", code)
+		callback.Set("state", query.Get("state"))
+		redirectURI.RawQuery = callback.Encode()
+		log.Printf("Approved %s for %s", userEmail, redirectURI.String())
+		http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+	})
+
+	http.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "invalid_request"})
+			return
+		}
+		if *clientSecret != "" {
+			id, secret, _ := r.BasicAuth()
+			id, _ = url.QueryUnescape(id)
+			secret, _ = url.QueryUnescape(secret)
+			if id != *clientID || subtle.ConstantTimeCompare([]byte(secret), []byte(*clientSecret)) != 1 {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+				return
+			}
+		}
+		if r.PostFormValue("grant_type") != "authorization_code" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+			return
+		}
+
+		mutex.Lock()
+		authorization, ok := codes[r.PostFormValue("code")]
+		delete(codes, r.PostFormValue("code"))
+		mutex.Unlock()
+
+		if !ok || time.Now().After(authorization.expiresAt) ||
+			authorization.redirectURI != r.PostFormValue("redirect_uri") ||
+			r.PostFormValue("client_id") != authorization.clientID ||
+			!verifyChallenge(authorization.codeChallenge, r.PostFormValue("code_verifier")) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		now := time.Now()
+		claims := jwt.MapClaims{
+			"iss":            *issuer,
+			"sub":            "mock|" + authorization.email,
+			"aud":            authorization.clientID,
+			"iat":            now.Unix(),
+			"exp":            now.Add(5 * time.Minute).Unix(),
+			"nonce":          authorization.nonce,
+			"email":          authorization.email,
+			"email_verified": !*unverified,
+			"name":           *name,
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = mockKeyID
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"access_token": randomString(),
+			"token_type":   "Bearer",
+			"expires_in":   300,
+			"id_token":     idToken,
+		})
+	})
+
+	fmt.Printf("✓ Mock OIDC provider %s listening on %s for client %s\n", *issuer, *addr, *clientID)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+// verifyChallenge checks the PKCE code verifier against the S256 challenge, if one was sent.
+func verifyChallenge(challenge string, verifier string) bool {
+	if challenge == "" {
+		return true
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
+
+func randomString() string {
+	raw := make([]byte, 24)
+	_, _ = rand.Read(raw)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"errors"
+	"health/models"
+	db "health/models/db"
+	"health/services"
+	"health/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Summary      Book an appointment
+// @Description  Book an appointment of the authenticated user with a doctor
+// @Tags         appointments
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  utils.Response
+// @Param        AppointmentRequest  body      models.AppointmentRequest  true  "Appointment details"
+// @Router       /v1/appointments [post]
+// @Security     ApiKeyAuth
+func BookAppointment(ctx *gin.Context) {
+	userId := ctx.MustGet("userId").(primitive.ObjectID)
+	var request models.AppointmentRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	doctorId, _ := primitive.ObjectIDFromHex(request.DoctorId)
+	appointment, err := services.BookAppointment(userId, doctorId, request.StartsAt, request.EndsAt, request.Reason)
+	if err != nil {
+		appointmentErrorResponse(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, appointment)
+}
+
+// @Summary      Get a list of my appointments
+// @Description  Get a paginated list of the authenticated user's appointments, most recent first
+// @Tags         appointments
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.PaginatedResponse
+// @Param        page   query     int  false  "Page number"     default(1)
+// @Param        limit  query     int  false  "Items per page"  default(10)
+// @Router       /v1/appointments [get]
+// @Security     ApiKeyAuth
+func GetAppointments(ctx *gin.Context) {
+	userId := ctx.MustGet("userId").(primitive.ObjectID)
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	appointments, total, err := services.GetPatientAppointments(ctx.Request.Context(), userId, page, limit)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.PaginatedSuccessResponse(ctx, appointments, page, limit, total)
+}
+
+// @Summary      Get upcoming appointments
+// @Description  Get a paginated list of booked appointments that have not ended yet, soonest first.
+// @Description  Doctors only see the appointments of their own profile.
+// @Tags         appointments
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.PaginatedResponse
+// @Param        doctor_id  query     string  false  "Only appointments with this doctor"
+// @Param        page       query     int     false  "Page number"     default(1)
+// @Param        limit      query     int     false  "Items per page"  default(10)
+// @Router       /v1/appointments/upcoming [get]
+// @Security     ApiKeyAuth
+func GetUpcomingAppointments(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+
+	var doctorId primitive.ObjectID
+	if id, exists := ctx.Get("doctorId"); exists {
+		doctorId = id.(primitive.ObjectID)
+	} else if ctx.GetString("role") == db.RoleDoctor {
+		doctor, err := services.FindDoctorByUser(ctx.MustGet("userId").(primitive.ObjectID))
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusForbidden, "no doctor profile is linked to this account")
+			return
+		}
+		doctorId = doctor.ID
+	} else if hex := ctx.Query("doctor_id"); hex != "" {
+		var err error
+		doctorId, err = primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid doctor id")
+			return
+		}
+	}
+
+	appointments, total, err := services.GetUpcomingAppointments(ctx.Request.Context(), doctorId, page, limit)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.PaginatedSuccessResponse(ctx, appointments, page, limit, total)
+}
+
+// @Summary      Get an appointment by ID
+// @Description  Get an appointment of the authenticated user
+// @Tags         appointments
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id   path      string  true  "Appointment ID"
+// @Router       /v1/appointments/{id} [get]
+// @Security     ApiKeyAuth
+func GetAppointment(ctx *gin.Context) {
+	appointment, ok := findManageableAppointment(ctx)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, appointment)
+}
+
+// @Summary      Reschedule an appointment
+// @Description  Move a booked appointment to another time with the same doctor
+// @Tags         appointments
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id                            path      string                               true  "Appointment ID"
+// @Param        RescheduleAppointmentRequest  body      models.RescheduleAppointmentRequest  true  "New time"
+// @Router       /v1/appointments/{id}/reschedule [put]
+// @Security     ApiKeyAuth
+func RescheduleAppointment(ctx *gin.Context) {
+	appointment, ok := findManageableAppointment(ctx)
+	if !ok {
+		return
+	}
+	var request models.RescheduleAppointmentRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	err := services.RescheduleAppointment(appointment, request.StartsAt, request.EndsAt)
+	if err != nil {
+		appointmentErrorResponse(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, appointment)
+}
+
+// @Summary      Cancel an appointment
+// @Description  Cancel a booked appointment, freeing its slot
+// @Tags         appointments
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id                        path      string                           true   "Appointment ID"
+// @Param        CancelAppointmentRequest  body      models.CancelAppointmentRequest  false  "Cancellation reason"
+// @Router       /v1/appointments/{id}/cancel [post]
+// @Security     ApiKeyAuth
+func CancelAppointment(ctx *gin.Context) {
+	appointment, ok := findManageableAppointment(ctx)
+	if !ok {
+		return
+	}
+	var request models.CancelAppointmentRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	err := services.CancelAppointment(appointment, ctx.MustGet("userId").(primitive.ObjectID), request.Reason)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, appointment)
+}
+
+// findManageableAppointment retrieves the appointment identified by the "id" path parameter.
+// PermissionMiddleware has already checked that the authenticated user is its patient, its doctor
+// or allowed to manage any appointment. If the appointment cannot be found, it sends an error
+// response and returns false.
+func findManageableAppointment(ctx *gin.Context) (*db.Appointment, bool) {
+	appointmentId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	appointment, err := services.FindAppointmentById(appointmentId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+
+	return appointment, true
+}
+
+// appointmentErrorResponse sends a 409 error response when the error reports a booking
+// conflict, and a 400 error response otherwise.
+func appointmentErrorResponse(ctx *gin.Context, err error) {
+	if errors.Is(err, services.ErrSlotTaken) {
+		utils.ErrorResponse(ctx, http.StatusConflict, err.Error())
+		return
+	}
+	utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+}
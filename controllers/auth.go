@@ -1,23 +1,26 @@
 package controllers
 
 import (
+	"errors"
 	"health/models"
 	db "health/models/db"
 	"health/services"
 	"health/utils"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // Register is an endpoint that creates a new user in the MongoDB database.
 // The request body must contain a name, email address and password.
 // The email address must be unique. The password is hashed using bcrypt.
-// The user is created with the role "user".
+// The user is created with the role "user" and an unverified email address,
+// and a verification email is sent to the address.
 // If the user cannot be created, an error is returned.
 // Otherwise, a JSON response with the user is sent.
 func Register(ctx *gin.Context) {
@@ -39,6 +42,9 @@ func Register(ctx *gin.Context) {
 		return
 	}
 
+	// the account exists either way, a failed email can be sent again with the resend endpoint
+	_ = services.SendEmailVerification(user)
+
 	utils.SuccessResponse(ctx, http.StatusOK, gin.H{
 		"user": user,
 	})
@@ -47,72 +53,161 @@ func Register(ctx *gin.Context) {
 // Login is an endpoint that verifies the given email and password.
 // If the verification succeeds, it generates new access tokens for the user.
 // The tokens are then sent in the response as JSON data.
-// If the verification fails, it sends a 400 error response with the error message.
+// If the verification fails, it sends a 400 error response with the same message for an unknown email
+// and a wrong password, and counts the failure. Repeated failures for an email or from an IP address
+// lock logins out for a while, during which a 429 error response with a Retry-After header is sent.
+// Suspended users are refused. When EMAIL_VERIFICATION_REQUIRED is set to login, users with an unverified email address are refused.
+// Users with 2FA enabled get a short-lived mfa token instead, to be exchanged with a code at /auth/mfa/verify.
 func Login(c *gin.Context) {
 	var requestBody models.LoginRequest
 	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
 
-	// get user by email
-	user, err := services.FindUserByEmail(requestBody.Email)
-	if err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	if lockedFor := services.LoginLockedFor(requestBody.Email, c.ClientIP()); lockedFor > 0 {
+		loginLockedResponse(c, &services.LoginLockedError{RetryAfter: lockedFor})
 		return
 	}
 
-	// check hashed password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(requestBody.Password))
+	user, err := services.AuthenticateUser(requestBody.Email, requestBody.Password)
 	if err != nil {
+		services.RecordLoginFailure(requestBody.Email, c.ClientIP(), c.Request.UserAgent())
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// generate new access tokens
-	accessToken, refreshToken, err := services.GenerateAccessTokens(user)
+	if user.IsSuspended() {
+		utils.ErrorResponse(c, http.StatusForbidden, "account is suspended")
+		return
+	}
+
+	if services.IsEmailVerificationRequired(models.EmailVerificationLogin) && !user.EmailVarified {
+		utils.ErrorResponse(c, http.StatusForbidden, "email address is not verified")
+		return
+	}
+
+	loginResponse(c, user)
+}
+
+// loginLockedResponse sends a 429 error response with a Retry-After header while logins are locked out.
+func loginLockedResponse(c *gin.Context, err *services.LoginLockedError) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(err.RetryAfter.Seconds()))))
+	utils.ErrorResponse(c, http.StatusTooManyRequests, err.Error())
+}
+
+// loginResponse sends the tokens of a new session to the authenticated user, or a short-lived
+// mfa token instead when the user has 2FA enabled. The failed logins of the user are only
+// forgotten once the tokens are issued, so that wrong 2FA codes keep counting towards the lockout.
+func loginResponse(c *gin.Context, user *db.User) {
+	if user.MFA.Enabled {
+		mfaToken, err := services.CreateMFAPendingToken(user)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.SuccessResponse(c, http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    mfaToken.GetResponseJson(),
+		})
+		return
+	}
+
+	// generate new access tokens in a new session
+	accessToken, refreshToken, err := services.GenerateAccessTokens(user, newSession(c, user))
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	services.ResetLoginFailures(user.Email)
 
 	utils.SuccessResponse(c, http.StatusOK, gin.H{
-		"user":    user,
-		"access":  accessToken.GetResponseJson(),
-		"refresh": refreshToken.GetResponseJson(),
+		"user":                    user,
+		"access":                  accessToken.GetResponseJson(),
+		"refresh":                 refreshToken.GetResponseJson(),
+		"mfa_enrollment_required": services.IsMFARequired(user),
 	})
 }
 
-// Refresh is a gin handler that refreshes an access token using a refresh token.
-// The handler expects a JSON body with a "token" field that contains the refresh token.
-// The handler will verify the token, find the associated user, delete the old token
-// and generate new access tokens. If the token is invalid, the associated user cannot
-// be found, the old token cannot be deleted, or the new tokens cannot be generated,
-// the handler will send a 400 error response with the error message. Otherwise, it will
-// send a 200 response with the user and the new tokens in the response body.
-func Refresh(c *gin.Context) {
-	var requestBody models.RefreshRequest
-	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+// OIDCLogin is an endpoint that starts a login with the OpenID Connect provider configured by OIDC_ISSUER.
+// It sends the URL of the provider to send the user to, and sets a cookie with the state the provider hands
+// back to OIDCCallback, so that only this browser can complete the login.
+// If no provider is configured, it sends a 404 error response.
+func OIDCLogin(c *gin.Context) {
+	if !services.IsOIDCEnabled() {
+		utils.ErrorResponse(c, http.StatusNotFound, "oidc login is not configured")
+		return
+	}
 
-	// check token validity
-	token, err := services.VerifyToken(requestBody.Token, db.TokenTypeRefresh)
+	authorizationURL, state, err := services.StartOIDCLogin()
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		utils.ErrorResponse(c, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(services.OIDCStateCookie, state, int(services.OIDCLoginLifetime.Seconds()), "/v1/auth/oidc",
+		"", gin.Mode() == gin.ReleaseMode, true)
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"authorization_url": authorizationURL,
+	})
+}
+
+// OIDCCallback is the endpoint the OpenID Connect provider redirects the user to with an authorization code and the state.
+// It exchanges the code for an ID token and logs in the user linked to the provider account,
+// linking or creating one by its verified email address first, like Login.
+// The state must match the cookie set by OIDCLogin in the same browser.
+// If the provider reported an error, or the login cannot be completed, it sends a 400 error response.
+// Suspended users are refused.
+func OIDCCallback(c *gin.Context) {
+	if !services.IsOIDCEnabled() {
+		utils.ErrorResponse(c, http.StatusNotFound, "oidc login is not configured")
 		return
 	}
 
-	user, err := services.FindUserById(token.User)
+	var requestQuery models.OIDCCallbackRequest
+	_ = c.ShouldBindQuery(&requestQuery)
+
+	if requestQuery.Error != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "identity provider refused the login: "+requestQuery.Error)
+		return
+	}
+
+	browserState, _ := c.Cookie(services.OIDCStateCookie)
+	c.SetCookie(services.OIDCStateCookie, "", -1, "/v1/auth/oidc", "", gin.Mode() == gin.ReleaseMode, true)
+
+	user, err := services.CompleteOIDCLogin(requestQuery.Code, requestQuery.State, browserState)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// delete old token
-	err = services.DeleteTokenById(token.ID)
+	if user.IsSuspended() {
+		utils.ErrorResponse(c, http.StatusForbidden, "account is suspended")
+		return
+	}
+
+	loginResponse(c, user)
+}
+
+// Refresh is a gin handler that refreshes an access token using a refresh token.
+// The handler expects a JSON body with a "token" field that contains the refresh token.
+// The handler will rotate the refresh token, which issues new access tokens in the same session.
+// A refresh token can only be used once; presenting it again revokes the whole session and sends
+// a 401 error response. If the token is invalid, the associated user or session cannot be found,
+// or the new tokens cannot be generated, the handler will send a 400 error response with the error
+// message. Otherwise, it will send a 200 response with the user and the new tokens in the response body.
+func Refresh(c *gin.Context) {
+	var requestBody models.RefreshRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	user, accessToken, refreshToken, err := services.RotateRefreshToken(requestBody.Token, c.Request.UserAgent(), c.ClientIP())
+	if errors.Is(err, services.ErrRefreshTokenReused) {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	accessToken, refreshToken, _ := services.GenerateAccessTokens(user)
-
 	utils.SuccessResponse(c, http.StatusOK, gin.H{
 		"user":    user,
 		"access":  accessToken.GetResponseJson(),
@@ -120,6 +215,11 @@ func Refresh(c *gin.Context) {
 	})
 }
 
+// newSession returns a new, unsaved session of the user for the client of the request.
+func newSession(c *gin.Context, user *db.User) *db.Session {
+	return db.NewSession(user.ID, c.Request.UserAgent(), c.ClientIP())
+}
+
 // GetAuthProfile is a gin handler that retrieves the user profile of the currently authenticated user.
 // The handler expects the user ID to be set in the gin context.
 // If the user ID is not set, the handler will send a 400 error response with the error message "cannot get user".
@@ -133,3 +233,274 @@ func GetAuthProfile(c *gin.Context) {
 	user, _ := services.FindUserById(userId.(primitive.ObjectID))
 	utils.SuccessResponse(c, http.StatusOK, user)
 }
+
+// VerifyEmail is a gin handler that marks the email address of a user as verified.
+// The handler expects the verification token sent by email in the "token" query parameter.
+// If the token is invalid, expired or already used, it sends a 400 error response with the error message.
+// Otherwise, it sends a 200 response with the verified user in the response body.
+func VerifyEmail(c *gin.Context) {
+	var request models.VerifyEmailRequest
+	_ = c.ShouldBindQuery(&request)
+
+	user, err := services.VerifyEmail(request.Token)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"user": user,
+	})
+}
+
+// ResendEmailVerification is a gin handler that sends a new verification email.
+// The handler expects a JSON body with the "email" of the account. It always answers with a
+// 200 response when the email is valid, so that it does not reveal which addresses are registered,
+// even if the email cannot be sent.
+func ResendEmailVerification(c *gin.Context) {
+	var request models.ResendEmailVerificationRequest
+	_ = c.ShouldBindBodyWith(&request, binding.JSON)
+
+	services.ResendEmailVerification(request.Email)
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"message": "if the address belongs to an unverified account, a verification email has been sent",
+	})
+}
+
+// ForgotPassword is a gin handler that mails a password reset token.
+// The handler expects a JSON body with the "email" of the account. It always answers with a
+// 200 response when the email is valid, so that it does not reveal which addresses are registered.
+// Too many requests for an email or from an IP address are refused with a 429 error response.
+// A token that cannot be sent is only logged, for the same reason.
+func ForgotPassword(c *gin.Context) {
+	var request models.ForgotPasswordRequest
+	_ = c.ShouldBindBodyWith(&request, binding.JSON)
+
+	if err := services.SendPasswordReset(request.Email, c.ClientIP()); err != nil {
+		utils.ErrorResponse(c, http.StatusTooManyRequests, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"message": "if the address belongs to an account, a password reset token has been sent",
+	})
+}
+
+// AcceptInvitation is a gin handler that creates the account of a staff invitation.
+// The handler expects a JSON body with the "token" sent by email and the "password" of the account.
+// The account gets the role the admin assigned, a verified email address and, for doctors, the doctor
+// profile of the invitation. The user is then logged in like with Login.
+// If the token is invalid, expired, replaced by a resent one, or its invitation was accepted or revoked,
+// it sends a 400 error response with the error message.
+func AcceptInvitation(c *gin.Context) {
+	var request models.AcceptInvitationRequest
+	_ = c.ShouldBindBodyWith(&request, binding.JSON)
+
+	user, err := services.AcceptInvitation(request.Token, request.Password)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	loginResponse(c, user)
+}
+
+// RequestMagicLink is a gin handler that emails a single-use login link to a user whose role may log in with one.
+// The handler expects a JSON body with the "email" of the account. The link only works on the device that
+// requested it: the response sets a cookie for browsers and returns the same "device_token", which apps send
+// in the X-Device-Token header instead. It always answers with a 200 response when the email is valid, so that
+// it does not reveal which addresses are registered. Too many requests for an email or from an IP address are
+// refused with a 429 error response. A link that cannot be sent is only logged, for the same reason. If the
+// device token cannot be generated, it sends a 500 error response.
+func RequestMagicLink(c *gin.Context) {
+	var request models.MagicLinkRequest
+	_ = c.ShouldBindBodyWith(&request, binding.JSON)
+
+	deviceToken, err := services.SendMagicLink(request.Email, c.ClientIP())
+	if errors.Is(err, services.ErrMagicLinkRateLimited) {
+		utils.ErrorResponse(c, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(services.MagicLinkDeviceCookie, deviceToken, services.Config.MagicLinkMinutes*60, "/v1/auth/magic-link",
+		"", gin.Mode() == gin.ReleaseMode, true)
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"message":      "if the address belongs to an account that can log in with a magic link, a link has been sent",
+		"device_token": deviceToken,
+	})
+}
+
+// VerifyMagicLink is a gin handler that logs in with the magic link token in the "token" query parameter,
+// from the device that requested it, like Login. The device is recognized by the cookie set when the link was
+// requested, or by the X-Device-Token header. The link verifies the email address of the user.
+// If the token is invalid, expired, already used or presented by another device, it sends a 400 error response
+// with the error message. Suspended users are refused.
+func VerifyMagicLink(c *gin.Context) {
+	var request models.VerifyMagicLinkRequest
+	_ = c.ShouldBindQuery(&request)
+
+	deviceToken := c.GetHeader(services.MagicLinkDeviceHeader)
+	if deviceToken == "" {
+		deviceToken, _ = c.Cookie(services.MagicLinkDeviceCookie)
+	}
+
+	user, err := services.ExchangeMagicLink(request.Token, deviceToken)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.SetCookie(services.MagicLinkDeviceCookie, "", -1, "/v1/auth/magic-link", "", gin.Mode() == gin.ReleaseMode, true)
+
+	if user.IsSuspended() {
+		utils.ErrorResponse(c, http.StatusForbidden, "account is suspended")
+		return
+	}
+
+	loginResponse(c, user)
+}
+
+// GetMagicLinkPolicy is a gin handler that sends the security settings, which hold the roles whose users
+// may log in with a magic link.
+func GetMagicLinkPolicy(c *gin.Context) {
+	utils.SuccessResponse(c, http.StatusOK, services.GetSecuritySettings())
+}
+
+// UpdateMagicLinkPolicy is a gin handler that sets the roles whose users may log in with a magic link.
+// The handler expects a JSON body with the "magic_link_roles". Links already sent to users of other roles stop working.
+// If the settings cannot be saved, it sends a 400 error response with the error message.
+func UpdateMagicLinkPolicy(c *gin.Context) {
+	var request models.MagicLinkPolicyRequest
+	_ = c.ShouldBindBodyWith(&request, binding.JSON)
+
+	settings, err := services.UpdateMagicLinkRoles(request.MagicLinkRoles)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, settings)
+}
+
+// ResetPassword is a gin handler that sets a new password with a password reset token.
+// The handler expects a JSON body with the "token" sent by email and the new "password".
+// All the access and refresh tokens of the user are revoked.
+// If the token is invalid, expired or already used, it sends a 400 error response with the error message.
+func ResetPassword(c *gin.Context) {
+	var request models.ResetPasswordRequest
+	_ = c.ShouldBindBodyWith(&request, binding.JSON)
+
+	if err := services.ResetPassword(request.Token, request.Password); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"message": "password has been reset, please log in again",
+	})
+}
+
+// ChangePassword is a gin handler that changes the password of the currently authenticated user.
+// The handler expects a JSON body with the "current_password" and the new "password".
+// All the access and refresh tokens of the user are revoked and a new pair is sent in the response,
+// so that only the current client stays logged in.
+// If the current password does not match, it sends a 400 error response with the error message.
+func ChangePassword(c *gin.Context) {
+	var request models.ChangePasswordRequest
+	_ = c.ShouldBindBodyWith(&request, binding.JSON)
+
+	user, err := services.FindUserById(c.MustGet("userId").(primitive.ObjectID))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := services.ChangePassword(user, request.CurrentPassword, request.Password); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accessToken, refreshToken, err := services.GenerateAccessTokens(user, newSession(c, user))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"user":    user,
+		"access":  accessToken.GetResponseJson(),
+		"refresh": refreshToken.GetResponseJson(),
+	})
+}
+
+// Logout is a gin handler that revokes the session of the access token presented by the currently
+// authenticated user, along with all the tokens of the session.
+// If the tokens cannot be revoked, it sends a 500 error response with the error message.
+func Logout(c *gin.Context) {
+	if err := services.RevokeToken(c.MustGet("token").(*db.Token)); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"message": "logged out",
+	})
+}
+
+// LogoutAll is a gin handler that revokes all the access and refresh tokens of the currently
+// authenticated user, which ends all their sessions including the current one.
+// If the tokens cannot be revoked, it sends a 500 error response with the error message.
+func LogoutAll(c *gin.Context) {
+	if err := services.RevokeUserTokens(c.MustGet("userId").(primitive.ObjectID)); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"message": "logged out of all sessions",
+	})
+}
+
+// @Summary      Get the sessions of the current user
+// @Description  Get the active sessions of the currently authenticated user, most recently used first
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Router       /v1/auth/sessions [get]
+// @Security     ApiKeyAuth
+func GetMySessions(c *gin.Context) {
+	sessions, err := services.GetUserSessions(c.MustGet("userId").(primitive.ObjectID))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	current := c.MustGet("token").(*db.Token).Session
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	utils.SuccessResponse(c, http.StatusOK, sessions)
+}
+
+// @Summary      Revoke a session of the current user
+// @Description  Revoke one session of the currently authenticated user along with all its tokens
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id   path      string  true  "Session ID"
+// @Router       /v1/auth/sessions/{id} [delete]
+// @Security     ApiKeyAuth
+func RevokeMySession(c *gin.Context) {
+	sessionId, _ := primitive.ObjectIDFromHex(c.Param("id"))
+	if err := services.RevokeSession(c.MustGet("userId").(primitive.ObjectID), sessionId); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Session revoked successfully")
+}
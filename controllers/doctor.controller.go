@@ -1,18 +1,367 @@
 package controllers
 
 import (
+	"errors"
+	"health/models"
+	db "health/models/db"
 	"health/services"
 	"health/utils"
+	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// @Summary      Get a list of doctors
+// @Description  Search the approved doctors, paginated. Given lat and lng, only doctors within the radius are
+// @Description  returned, nearest first, each with its distance in kilometers.
+// @Tags         doctors
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.PaginatedResponse
+// @Param        page            query     int     false  "Page number"     default(1)
+// @Param        limit           query     int     false  "Items per page"  default(10)
+// @Param        name            query     string  false  "Name prefix, case-insensitive"
+// @Param        specialization  query     string  false  "Exact specialization"
+// @Param        location        query     string  false  "Location prefix, case-insensitive"
+// @Param        available       query     bool    false  "Only doctors that are (not) available right now"
+// @Param        day             query     string  false  "Lowercase weekday the doctor works on"
+// @Param        min_experience  query     int     false  "Minimum years of experience"
+// @Param        sort            query     string  false  "name or experience"  default(name)
+// @Param        order           query     string  false  "asc or desc"         default(asc)
+// @Param        lat             query     number  false  "Latitude of the searched point"
+// @Param        lng             query     number  false  "Longitude of the searched point"
+// @Param        radius          query     number  false  "Search radius in kilometers"  default(10)
+// @Router       /v1/doctor/list [get]
+// @Security     ApiKeyAuth
 func GetDoctors(ctx *gin.Context) {
 	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
-	nameFilter := ctx.Query("name")
-	users, total, _ := services.GetDoctors(ctx.Request.Context(), page, limit, nameFilter)
+	var search models.DoctorSearchRequest
+	_ = ctx.ShouldBindQuery(&search)
+	if search.IsNearby() {
+		doctors, total, err := services.GetNearbyDoctors(ctx.Request.Context(), page, limit, &search)
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.PaginatedSuccessResponse(ctx, doctors, page, limit, total)
+		return
+	}
+	doctors, total, err := services.GetDoctors(ctx.Request.Context(), page, limit, &search)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	utils.PaginatedSuccessResponse(ctx, users, page, limit, total)
+	utils.PaginatedSuccessResponse(ctx, doctors, page, limit, total)
+}
+
+// @Summary      Create a doctor
+// @Description  Create a new doctor profile with its weekly schedule
+// @Tags         doctors
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  utils.Response
+// @Param        DoctorRequest  body      models.DoctorRequest  true  "Doctor details"
+// @Router       /v1/doctor [post]
+// @Security     ApiKeyAuth
+func CreateDoctor(ctx *gin.Context) {
+	var request models.DoctorRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	doctor, err := services.CreateDoctor(&request)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, doctor)
+}
+
+// @Summary      Get a doctor by ID
+// @Description  Get a doctor profile by the given ID
+// @Tags         doctors
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id   path      string  true  "Doctor ID"
+// @Router       /v1/doctor/{id} [get]
+// @Security     ApiKeyAuth
+func GetDoctor(ctx *gin.Context) {
+	doctorId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	doctor, err := services.FindDoctorById(doctorId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, doctor)
+}
+
+// @Summary      Update a doctor
+// @Description  Replace the profile and weekly schedule of a doctor
+// @Tags         doctors
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id             path      string                true  "Doctor ID"
+// @Param        DoctorRequest  body      models.DoctorRequest  true  "Doctor details"
+// @Router       /v1/doctor/{id} [put]
+// @Security     ApiKeyAuth
+func UpdateDoctor(ctx *gin.Context) {
+	doctorId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	var request models.DoctorRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	doctor, err := services.UpdateDoctor(doctorId, &request)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, doctor)
+}
+
+// @Summary      Delete a doctor
+// @Description  Delete a doctor that has no upcoming appointments
+// @Tags         doctors
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id   path      string  true  "Doctor ID"
+// @Router       /v1/doctor/{id} [delete]
+// @Security     ApiKeyAuth
+func DeleteDoctor(ctx *gin.Context) {
+	doctorId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+
+	err := services.DeleteDoctor(ctx.Request.Context(), doctorId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Doctor deleted successfully")
+}
+
+// @Summary      Get free slots of a doctor
+// @Description  Expand the doctor's schedule into bookable slots, leaving out booked time
+// @Tags         doctors
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id        path      string  true   "Doctor ID"
+// @Param        from      query     string  false  "RFC 3339 time or YYYY-MM-DD date, defaults to now"
+// @Param        to        query     string  false  "RFC 3339 time or YYYY-MM-DD date, defaults to 7 days after from"
+// @Param        duration  query     int     false  "Slot length in minutes, defaults to APPOINTMENT_SLOT_MINUTES"
+// @Router       /v1/doctor/{id}/slots [get]
+// @Security     ApiKeyAuth
+func GetDoctorSlots(ctx *gin.Context) {
+	doctorId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	doctor, err := services.FindDoctorById(doctorId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+
+	from, err := parseTimeQuery(ctx, "from", time.Now())
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	to, err := parseTimeQuery(ctx, "to", from.AddDate(0, 0, 7))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	duration, err := strconv.Atoi(ctx.DefaultQuery("duration", strconv.Itoa(services.Config.AppointmentSlotMinutes)))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid duration")
+		return
+	}
+
+	slots, err := services.GetAvailableSlots(doctor, from, to, time.Duration(duration)*time.Minute)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, slots)
+}
+
+// parseTimeQuery parses the query parameter with the given key as an RFC 3339 time or a
+// YYYY-MM-DD date in UTC. If the parameter is missing, the fallback is returned.
+func parseTimeQuery(ctx *gin.Context, key string, fallback time.Time) (time.Time, error) {
+	value := ctx.Query(key)
+	if value == "" {
+		return fallback, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	if parsed, err := time.Parse("2006-01-02", value); err == nil {
+		return parsed, nil
+	}
+	return time.Time{}, errors.New("invalid " + key + ", expected an RFC 3339 time or a YYYY-MM-DD date")
+}
+
+// @Summary      Get schedule exceptions of a doctor
+// @Description  Get the time off and extra hours of the doctor that have not ended yet
+// @Tags         doctors
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id   path      string  true  "Doctor ID"
+// @Router       /v1/doctor/{id}/exceptions [get]
+// @Security     ApiKeyAuth
+func GetDoctorExceptions(ctx *gin.Context) {
+	doctorId := doctorIdParam(ctx)
+	exceptions, err := services.GetScheduleExceptions(doctorId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, exceptions)
+}
+
+// @Summary      Add a schedule exception to a doctor
+// @Description  Block time off or add extra hours without changing the weekly schedule. Time off is refused while appointments are booked in it
+// @Tags         doctors
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  utils.Response
+// @Param        id                        path      string                           true  "Doctor ID"
+// @Param        ScheduleExceptionRequest  body      models.ScheduleExceptionRequest  true  "Exception details"
+// @Router       /v1/doctor/{id}/exceptions [post]
+// @Security     ApiKeyAuth
+func CreateDoctorException(ctx *gin.Context) {
+	doctorId := doctorIdParam(ctx)
+	if _, err := services.FindDoctorById(doctorId); err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+	var request models.ScheduleExceptionRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	userId := ctx.MustGet("userId").(primitive.ObjectID)
+	exception, err := services.CreateScheduleException(doctorId, request.Type, request.StartsAt, request.EndsAt, request.Reason, userId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, exception)
+}
+
+// @Summary      Delete a schedule exception of a doctor
+// @Description  Remove time off or extra hours from the doctor's schedule
+// @Tags         doctors
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id           path      string  true  "Doctor ID"
+// @Param        exceptionId  path      string  true  "Exception ID"
+// @Router       /v1/doctor/{id}/exceptions/{exceptionId} [delete]
+// @Security     ApiKeyAuth
+func DeleteDoctorException(ctx *gin.Context) {
+	doctorId := doctorIdParam(ctx)
+	exceptionId, err := primitive.ObjectIDFromHex(ctx.Param("exceptionId"))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid exception id")
+		return
+	}
+
+	err = services.DeleteScheduleException(doctorId, exceptionId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Schedule exception deleted successfully")
+}
+
+// @Summary      Get my doctor profile
+// @Description  Get the doctor profile linked to the authenticated user
+// @Tags         doctors
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Router       /v1/doctor/me [get]
+// @Security     ApiKeyAuth
+func GetMyDoctorProfile(ctx *gin.Context) {
+	doctor, err := services.FindDoctorById(doctorIdParam(ctx))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, doctor)
+}
+
+// @Summary      Update my doctor profile
+// @Description  Update the contact details and availability of the doctor profile linked to the authenticated user
+// @Tags         doctors
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        DoctorProfileRequest  body      models.DoctorProfileRequest  true  "Profile details"
+// @Router       /v1/doctor/me [put]
+// @Security     ApiKeyAuth
+func UpdateMyDoctorProfile(ctx *gin.Context) {
+	doctor, err := services.FindDoctorById(doctorIdParam(ctx))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+	var request models.DoctorProfileRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	err = services.UpdateDoctorProfile(doctor, &request)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, doctor)
+}
+
+// @Summary      Update my weekly schedule
+// @Description  Replace the weekly schedule of the doctor profile linked to the authenticated user
+// @Tags         doctors
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        WeeklySchedule  body      db.WeeklySchedule  true  "Weekly schedule"
+// @Router       /v1/doctor/me/schedule [put]
+// @Security     ApiKeyAuth
+func UpdateMyDoctorSchedule(ctx *gin.Context) {
+	doctor, err := services.FindDoctorById(doctorIdParam(ctx))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+	var schedule db.WeeklySchedule
+	_ = ctx.ShouldBindBodyWith(&schedule, binding.JSON)
+
+	err = services.UpdateDoctorSchedule(doctor, schedule)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, doctor)
+}
+
+// doctorIdParam returns the doctor id set by middlewares.DoctorProfileMiddleware,
+// or the "id" path parameter when the route addresses a doctor by id.
+func doctorIdParam(ctx *gin.Context) primitive.ObjectID {
+	if doctorId, exists := ctx.Get("doctorId"); exists {
+		return doctorId.(primitive.ObjectID)
+	}
+	doctorId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	return doctorId
 }
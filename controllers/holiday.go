@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"health/models"
+	"health/services"
+	"health/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Summary      Get clinic holidays
+// @Description  Get the clinic-wide holidays, optionally between two dates
+// @Tags         holidays
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        from  query     string  false  "First date, YYYY-MM-DD"
+// @Param        to    query     string  false  "Last date, YYYY-MM-DD"
+// @Router       /v1/holidays [get]
+// @Security     ApiKeyAuth
+func GetHolidays(ctx *gin.Context) {
+	holidays, err := services.GetHolidays(ctx.Query("from"), ctx.Query("to"))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, holidays)
+}
+
+// @Summary      Create a clinic holiday
+// @Description  Block a date for every doctor
+// @Tags         holidays
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  utils.Response
+// @Param        HolidayRequest  body      models.HolidayRequest  true  "Holiday details"
+// @Router       /v1/holidays [post]
+// @Security     ApiKeyAuth
+func CreateHoliday(ctx *gin.Context) {
+	var request models.HolidayRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	holiday, err := services.CreateHoliday(request.Date, request.Name)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, holiday)
+}
+
+// @Summary      Update a clinic holiday
+// @Description  Move or rename a clinic holiday
+// @Tags         holidays
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id              path      string                 true  "Holiday ID"
+// @Param        HolidayRequest  body      models.HolidayRequest  true  "Holiday details"
+// @Router       /v1/holidays/{id} [put]
+// @Security     ApiKeyAuth
+func UpdateHoliday(ctx *gin.Context) {
+	holidayId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	var request models.HolidayRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	holiday, err := services.UpdateHoliday(holidayId, request.Date, request.Name)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, holiday)
+}
+
+// @Summary      Delete a clinic holiday
+// @Description  Delete a clinic holiday
+// @Tags         holidays
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id   path      string  true  "Holiday ID"
+// @Router       /v1/holidays/{id} [delete]
+// @Security     ApiKeyAuth
+func DeleteHoliday(ctx *gin.Context) {
+	holidayId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+
+	err := services.DeleteHoliday(holidayId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Holiday deleted successfully")
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"health/models"
+	"health/services"
+	"health/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Summary      Invite a staff member
+// @Description  Email an invitation to create an account with the given role, optionally linked to a doctor profile.
+// @Description  The invitee accepts it at /v1/auth/accept-invitation.
+// @Tags         invitations
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  utils.Response
+// @Param        InvitationRequest  body  models.InvitationRequest  true  "Email, name, role and optional doctor profile"
+// @Router       /v1/invitations [post]
+// @Security     ApiKeyAuth
+func CreateInvitation(ctx *gin.Context) {
+	var request models.InvitationRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	doctorId, _ := primitive.ObjectIDFromHex(request.DoctorId)
+	invitation, err := services.CreateInvitation(ctx.MustGet("userId").(primitive.ObjectID), request.Email, request.Name, request.Role, doctorId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, invitation)
+}
+
+// @Summary      Get pending invitations
+// @Description  Get the invitations that have been neither accepted nor revoked, newest first, including expired ones
+// @Tags         invitations
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Router       /v1/invitations [get]
+// @Security     ApiKeyAuth
+func GetPendingInvitations(ctx *gin.Context) {
+	invitations, err := services.GetPendingInvitations()
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, invitations)
+}
+
+// @Summary      Resend an invitation
+// @Description  Email a new token for a pending invitation, with a new expiry; the tokens sent before stop working
+// @Tags         invitations
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id   path      string  true  "Invitation ID"
+// @Router       /v1/invitations/{id}/resend [post]
+// @Security     ApiKeyAuth
+func ResendInvitation(ctx *gin.Context) {
+	invitationId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	invitation, err := services.ResendInvitation(invitationId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, invitation)
+}
+
+// @Summary      Revoke an invitation
+// @Description  Revoke a pending invitation, so that its token stops working
+// @Tags         invitations
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id   path      string  true  "Invitation ID"
+// @Router       /v1/invitations/{id} [delete]
+// @Security     ApiKeyAuth
+func RevokeInvitation(ctx *gin.Context) {
+	invitationId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err := services.RevokeInvitation(invitationId); err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Invitation revoked successfully")
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"errors"
+	"health/models"
+	"health/services"
+	"health/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Summary      Enroll an authenticator
+// @Description  Generate a TOTP secret and its otpauth URI for the current user; 2FA is enabled once a code confirms it
+// @Tags         mfa
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Router       /v1/auth/mfa/totp/enroll [post]
+// @Security     ApiKeyAuth
+func EnrollTOTP(ctx *gin.Context) {
+	user, err := services.FindUserById(ctx.MustGet("userId").(primitive.ObjectID))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	enrollment, err := services.EnrollTOTP(user)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, enrollment)
+}
+
+// @Summary      Confirm an authenticator
+// @Description  Enable 2FA with a first code of the enrolled secret; the recovery codes are only shown in this response
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        ConfirmTOTPRequest  body  models.ConfirmTOTPRequest  true  "Code"
+// @Router       /v1/auth/mfa/totp/confirm [post]
+// @Security     ApiKeyAuth
+func ConfirmTOTP(ctx *gin.Context) {
+	var request models.ConfirmTOTPRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	user, err := services.FindUserById(ctx.MustGet("userId").(primitive.ObjectID))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	codes, err := services.ConfirmTOTP(user, request.Code)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// @Summary      Disable 2FA
+// @Description  Disable 2FA for the current user with their password and a code or recovery code
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        DisableMFARequest  body  models.DisableMFARequest  true  "Password and code"
+// @Router       /v1/auth/mfa/totp/disable [post]
+// @Security     ApiKeyAuth
+func DisableTOTP(ctx *gin.Context) {
+	var request models.DisableMFARequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	user, err := services.FindUserById(ctx.MustGet("userId").(primitive.ObjectID))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := services.DisableTOTP(user, request.Password, request.Code, ctx.ClientIP(), ctx.Request.UserAgent()); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Two-factor authentication disabled")
+}
+
+// @Summary      Regenerate recovery codes
+// @Description  Replace the recovery codes of the current user after checking a code; the new codes are only shown in this response
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        MFACodeRequest  body  models.MFACodeRequest  true  "Code"
+// @Router       /v1/auth/mfa/recovery-codes [post]
+// @Security     ApiKeyAuth
+func RegenerateRecoveryCodes(ctx *gin.Context) {
+	var request models.MFACodeRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	user, err := services.FindUserById(ctx.MustGet("userId").(primitive.ObjectID))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	codes, err := services.RegenerateRecoveryCodes(user, request.Code, ctx.ClientIP(), ctx.Request.UserAgent())
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// @Summary      Complete a 2FA login
+// @Description  Exchange the mfa token returned by the login and a code or recovery code for access tokens.
+// @Description  Wrong codes count as failed logins; while the account or IP address is locked out, 429 is returned.
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        VerifyMFARequest  body  models.VerifyMFARequest  true  "MFA token and code"
+// @Router       /v1/auth/mfa/verify [post]
+func VerifyMFA(ctx *gin.Context) {
+	var request models.VerifyMFARequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	user, err := services.ExchangeMFAPendingToken(request.MFAToken, request.Code, ctx.ClientIP(), ctx.Request.UserAgent())
+	var lockedErr *services.LoginLockedError
+	if errors.As(err, &lockedErr) {
+		loginLockedResponse(ctx, lockedErr)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if user.IsSuspended() {
+		utils.ErrorResponse(ctx, http.StatusForbidden, "account is suspended")
+		return
+	}
+
+	accessToken, refreshToken, err := services.GenerateAccessTokens(user, newSession(ctx, user))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	services.ResetLoginFailures(user.Email)
+
+	utils.SuccessResponse(ctx, http.StatusOK, gin.H{
+		"user":    user,
+		"access":  accessToken.GetResponseJson(),
+		"refresh": refreshToken.GetResponseJson(),
+	})
+}
+
+// @Summary      Get the 2FA policy
+// @Description  Get the roles whose users must enable 2FA
+// @Tags         mfa
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Router       /v1/auth/mfa/policy [get]
+// @Security     ApiKeyAuth
+func GetMFAPolicy(ctx *gin.Context) {
+	utils.SuccessResponse(ctx, http.StatusOK, services.GetSecuritySettings())
+}
+
+// @Summary      Update the 2FA policy
+// @Description  Set the roles whose users must enable 2FA; until they do, they can only reach the /v1/auth endpoints
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        MFAPolicyRequest  body  models.MFAPolicyRequest  true  "Roles"
+// @Router       /v1/auth/mfa/policy [put]
+// @Security     ApiKeyAuth
+func UpdateMFAPolicy(ctx *gin.Context) {
+	var request models.MFAPolicyRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	settings, err := services.UpdateMFARequiredRoles(request.MFARequiredRoles)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, settings)
+}
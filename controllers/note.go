@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"health/models"
+	"health/services"
+	"health/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Summary      Create a note
+// @Description  Create a new note owned by the authenticated user
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  utils.Response
+// @Param        NoteRequest  body      models.NoteRequest  true  "Note details"
+// @Router       /v1/notes [post]
+// @Security     ApiKeyAuth
+func CreateNote(ctx *gin.Context) {
+	userId := ctx.MustGet("userId").(primitive.ObjectID)
+	var request models.NoteRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	note, err := services.CreateNote(userId, request.Title, request.Content)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	services.CacheOneNote(userId, note)
+
+	utils.SuccessResponse(ctx, http.StatusCreated, note)
+}
+
+// @Summary      Get a list of notes
+// @Description  Get a paginated list of the authenticated user's notes
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.PaginatedResponse
+// @Param        page   query     int  false  "Page number"     default(1)
+// @Param        limit  query     int  false  "Items per page"  default(10)
+// @Router       /v1/notes [get]
+// @Security     ApiKeyAuth
+func GetNotes(ctx *gin.Context) {
+	userId := ctx.MustGet("userId").(primitive.ObjectID)
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	notes, total, err := services.GetNotes(ctx.Request.Context(), userId, page, limit)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.PaginatedSuccessResponse(ctx, notes, page, limit, total)
+}
+
+// @Summary      Get a note by ID
+// @Description  Get one of the authenticated user's notes, served from cache when USE_REDIS is enabled
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id   path      string  true  "Note ID"
+// @Router       /v1/notes/{id} [get]
+// @Security     ApiKeyAuth
+func GetNote(ctx *gin.Context) {
+	userId := ctx.MustGet("userId").(primitive.ObjectID)
+	noteId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+
+	note, err := services.GetNoteFromCache(userId, noteId)
+	if err == nil {
+		utils.SuccessResponse(ctx, http.StatusOK, note)
+		return
+	}
+
+	note, err = services.GetNoteById(userId, noteId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+	services.CacheOneNote(userId, note)
+
+	utils.SuccessResponse(ctx, http.StatusOK, note)
+}
+
+// @Summary      Update a note
+// @Description  Update one of the authenticated user's notes
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id           path      string              true  "Note ID"
+// @Param        NoteRequest  body      models.NoteRequest  true  "Note details"
+// @Router       /v1/notes/{id} [put]
+// @Security     ApiKeyAuth
+func UpdateNote(ctx *gin.Context) {
+	userId := ctx.MustGet("userId").(primitive.ObjectID)
+	noteId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	var request models.NoteRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	note, err := services.UpdateNote(userId, noteId, &request)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	services.CacheOneNote(userId, note)
+
+	utils.SuccessResponse(ctx, http.StatusOK, note)
+}
+
+// @Summary      Delete a note
+// @Description  Delete one of the authenticated user's notes
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id   path      string  true  "Note ID"
+// @Router       /v1/notes/{id} [delete]
+// @Security     ApiKeyAuth
+func DeleteNote(ctx *gin.Context) {
+	userId := ctx.MustGet("userId").(primitive.ObjectID)
+	noteId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+
+	err := services.DeleteNote(userId, noteId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	services.DeleteNoteFromCache(userId, noteId)
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Note deleted successfully")
+}
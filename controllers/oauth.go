@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"errors"
+	"health/models"
+	db "health/models/db"
+	"health/services"
+	"health/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Summary      Register an OAuth client
+// @Description  Register a third-party app. The secret of a confidential client is only shown in this response.
+// @Tags         oauth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        OAuthClientRequest  body  models.OAuthClientRequest  true  "Client settings"
+// @Router       /v1/oauth/clients [post]
+// @Security     ApiKeyAuth
+func CreateOAuthClient(ctx *gin.Context) {
+	var request models.OAuthClientRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	userId, _ := primitive.ObjectIDFromHex(request.User)
+	client, secret, err := services.CreateOAuthClient(ctx.MustGet("userId").(primitive.ObjectID), &services.OAuthClientRegistration{
+		Name:         request.Name,
+		Confidential: request.Confidential,
+		RedirectURIs: request.RedirectURIs,
+		GrantTypes:   request.GrantTypes,
+		Scopes:       request.Scopes,
+		User:         userId,
+	})
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, gin.H{
+		"client":        client,
+		"client_id":     client.ID.Hex(),
+		"client_secret": secret,
+	})
+}
+
+// @Summary      Get the OAuth clients
+// @Description  Get all the registered OAuth clients, newest first, including revoked ones
+// @Tags         oauth
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Router       /v1/oauth/clients [get]
+// @Security     ApiKeyAuth
+func GetOAuthClients(ctx *gin.Context) {
+	clients, err := services.GetOAuthClients()
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, clients)
+}
+
+// @Summary      Revoke an OAuth client
+// @Description  Revoke an OAuth client along with all the tokens issued to it
+// @Tags         oauth
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id   path      string  true  "Client ID"
+// @Router       /v1/oauth/clients/{id} [delete]
+// @Security     ApiKeyAuth
+func RevokeOAuthClient(ctx *gin.Context) {
+	clientId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err := services.RevokeOAuthClient(clientId); err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "OAuth client revoked successfully")
+}
+
+// @Summary      Get an authorization request
+// @Description  Check an authorization code request of a third-party app and describe it for the consent screen
+// @Tags         oauth
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        response_type          query  string  true   "code"
+// @Param        client_id              query  string  true   "Client ID"
+// @Param        redirect_uri           query  string  true   "Registered redirect URI"
+// @Param        scope                  query  string  true   "Space separated permissions"
+// @Param        state                  query  string  false  "Opaque value returned to the client"
+// @Param        code_challenge         query  string  true   "PKCE code challenge"
+// @Param        code_challenge_method  query  string  true   "S256"
+// @Router       /v1/oauth/authorize [get]
+// @Security     ApiKeyAuth
+func GetOAuthAuthorization(ctx *gin.Context) {
+	var request models.OAuthAuthorizeRequest
+	_ = ctx.ShouldBindQuery(&request)
+
+	_, authorization, ok := prepareOAuthAuthorization(ctx, &request)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, gin.H{
+		"client": gin.H{
+			"client_id": authorization.Client.ID.Hex(),
+			"name":      authorization.Client.Name,
+		},
+		"scopes":       authorization.Scopes,
+		"redirect_uri": authorization.RedirectURI,
+		"state":        authorization.State,
+	})
+}
+
+// @Summary      Answer an authorization request
+// @Description  Approve or deny an authorization code request of a third-party app.
+// @Description  The response holds the URI to redirect the browser to, carrying either the code or an access_denied error.
+// @Tags         oauth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        OAuthAuthorizeRequest  body  models.OAuthAuthorizeRequest  true  "Authorization request and decision"
+// @Router       /v1/oauth/authorize [post]
+// @Security     ApiKeyAuth
+func DecideOAuthAuthorization(ctx *gin.Context) {
+	var request models.OAuthAuthorizeRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	user, authorization, ok := prepareOAuthAuthorization(ctx, &request)
+	if !ok {
+		return
+	}
+
+	if !request.Approve {
+		utils.SuccessResponse(ctx, http.StatusOK, gin.H{"redirect_uri": services.DenyOAuthAuthorization(authorization)})
+		return
+	}
+
+	redirectURI, err := services.ApproveOAuthAuthorization(user, authorization)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, gin.H{"redirect_uri": redirectURI})
+}
+
+// prepareOAuthAuthorization checks the authorization request for the authenticated user.
+// If the request is invalid, it sends a 400 error response and returns false.
+func prepareOAuthAuthorization(ctx *gin.Context, request *models.OAuthAuthorizeRequest) (*db.User, *services.OAuthAuthorization, bool) {
+	user, err := services.FindUserById(ctx.MustGet("userId").(primitive.ObjectID))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	}
+
+	authorization, err := services.PrepareOAuthAuthorization(user, request.ClientID, request.RedirectURI, request.Scope, request.State, request.CodeChallenge)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	}
+
+	return user, authorization, true
+}
+
+// @Summary      Get OAuth tokens
+// @Description  Exchange an authorization code, a refresh token or client credentials for tokens, as described by RFC 6749.
+// @Description  Clients authenticate with HTTP Basic or the client_id and client_secret parameters.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type     formData  string  true   "authorization_code, refresh_token or client_credentials"
+// @Param        code           formData  string  false  "Authorization code"
+// @Param        redirect_uri   formData  string  false  "Redirect URI of the authorization request"
+// @Param        code_verifier  formData  string  false  "PKCE code verifier"
+// @Param        refresh_token  formData  string  false  "Refresh token"
+// @Param        scope          formData  string  false  "Space separated permissions, for client credentials"
+// @Router       /v1/oauth/token [post]
+func OAuthToken(ctx *gin.Context) {
+	var request models.OAuthTokenRequest
+	_ = ctx.ShouldBindWith(&request, binding.Form)
+	if err := request.Validate(); err != nil {
+		oauthErrorResponse(ctx, &services.OAuthError{Code: "invalid_request", Description: err.Error()})
+		return
+	}
+
+	client, err := authenticateOAuthClient(ctx, request.ClientID, request.ClientSecret)
+	if err != nil {
+		oauthErrorResponse(ctx, err)
+		return
+	}
+
+	var accessToken, refreshToken *db.Token
+	switch request.GrantType {
+	case db.OAuthGrantAuthorizationCode:
+		accessToken, refreshToken, err = services.ExchangeOAuthAuthorizationCode(client, request.Code, request.RedirectURI, request.CodeVerifier, ctx.ClientIP())
+	case db.OAuthGrantRefreshToken:
+		accessToken, refreshToken, err = services.RotateOAuthRefreshToken(client, request.RefreshToken, ctx.ClientIP())
+	case db.OAuthGrantClientCredentials:
+		accessToken, err = services.IssueClientCredentialsToken(client, request.Scope)
+	}
+	if err != nil {
+		oauthErrorResponse(ctx, err)
+		return
+	}
+
+	response := gin.H{
+		"access_token": accessToken.Token,
+		"token_type":   "Bearer",
+		"expires_in":   int(time.Until(accessToken.ExpriesAt).Seconds()),
+		"scope":        strings.Join(accessToken.Scopes, " "),
+	}
+	if refreshToken != nil {
+		response["refresh_token"] = refreshToken.Token
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Summary      Introspect an OAuth token
+// @Description  Describe a token issued to the authenticated client, as described by RFC 7662
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Access or refresh token"
+// @Param        token_type_hint  formData  string  false  "access_token or refresh_token"
+// @Router       /v1/oauth/introspect [post]
+func IntrospectOAuthToken(ctx *gin.Context) {
+	var request models.OAuthTokenHintRequest
+	_ = ctx.ShouldBindWith(&request, binding.Form)
+	if err := request.Validate(); err != nil {
+		oauthErrorResponse(ctx, &services.OAuthError{Code: "invalid_request", Description: err.Error()})
+		return
+	}
+
+	client, err := authenticateOAuthClient(ctx, request.ClientID, request.ClientSecret)
+	if err != nil {
+		oauthErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	token := services.IntrospectOAuthToken(client, request.Token, request.TokenTypeHint)
+	if token == nil {
+		ctx.JSON(http.StatusOK, gin.H{"active": false})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"active":     true,
+		"scope":      strings.Join(token.Scopes, " "),
+		"client_id":  token.Client.Hex(),
+		"sub":        token.User.Hex(),
+		"token_type": "Bearer",
+		"exp":        token.ExpriesAt.Unix(),
+		"iat":        token.CreatedAt.Unix(),
+	})
+}
+
+// @Summary      Revoke an OAuth token
+// @Description  Revoke a token issued to the authenticated client, as described by RFC 7009
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Access or refresh token"
+// @Param        token_type_hint  formData  string  false  "access_token or refresh_token"
+// @Router       /v1/oauth/revoke [post]
+func RevokeOAuthToken(ctx *gin.Context) {
+	var request models.OAuthTokenHintRequest
+	_ = ctx.ShouldBindWith(&request, binding.Form)
+	if err := request.Validate(); err != nil {
+		oauthErrorResponse(ctx, &services.OAuthError{Code: "invalid_request", Description: err.Error()})
+		return
+	}
+
+	client, err := authenticateOAuthClient(ctx, request.ClientID, request.ClientSecret)
+	if err != nil {
+		oauthErrorResponse(ctx, err)
+		return
+	}
+
+	services.RevokeOAuthToken(client, request.Token, request.TokenTypeHint)
+	ctx.Status(http.StatusOK)
+}
+
+// authenticateOAuthClient authenticates the client with HTTP Basic credentials, or with the
+// client_id and client_secret parameters when the request has none.
+func authenticateOAuthClient(ctx *gin.Context, clientId string, clientSecret string) (*db.OAuthClient, error) {
+	if id, secret, ok := ctx.Request.BasicAuth(); ok {
+		clientId, clientSecret = id, secret
+	}
+
+	return services.AuthenticateOAuthClient(clientId, clientSecret)
+}
+
+// oauthErrorResponse sends the error in the format of RFC 6749: 401 for a failed client
+// authentication, and 400 otherwise. Errors that are not an OAuthError are server errors.
+func oauthErrorResponse(ctx *gin.Context, err error) {
+	var oauthErr *services.OAuthError
+	if !errors.As(err, &oauthErr) {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "server_error", "error_description": err.Error()})
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == "invalid_client" {
+		ctx.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		status = http.StatusUnauthorized
+	}
+	ctx.AbortWithStatusJSON(status, gin.H{"error": oauthErr.Code, "error_description": oauthErr.Description})
+}
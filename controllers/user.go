@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"health/models"
+	db "health/models/db"
 	"health/services"
 	"health/utils"
 	"health/utils/requests"
@@ -95,3 +97,294 @@ func Delete(ctx *gin.Context) {
 
 	utils.SuccessResponse(ctx, http.StatusOK, "User deleted successfully")
 }
+
+// @Summary      Get the sessions of a user
+// @Description  Get the active sessions of the user with the given ID, most recently used first
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id   path      string  true  "User ID"
+// @Router       /v1/user/{id}/sessions [get]
+// @Security     ApiKeyAuth
+func GetUserSessions(ctx *gin.Context) {
+	userId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	sessions, err := services.GetUserSessions(userId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, sessions)
+}
+
+// @Summary      Revoke a session of a user
+// @Description  Revoke one session of the user with the given ID along with all its tokens
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id         path      string  true  "User ID"
+// @Param        sessionId  path      string  true  "Session ID"
+// @Router       /v1/user/{id}/sessions/{sessionId} [delete]
+// @Security     ApiKeyAuth
+func RevokeUserSession(ctx *gin.Context) {
+	userId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	sessionId, err := primitive.ObjectIDFromHex(ctx.Param("sessionId"))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid session id")
+		return
+	}
+
+	if err := services.RevokeSession(userId, sessionId); err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Session revoked successfully")
+}
+
+// @Summary      Unlock a user
+// @Description  Lift the login lockout of the user with the given ID and reset their failed login counter
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id   path      string  true  "User ID"
+// @Router       /v1/user/{id}/unlock [post]
+// @Security     ApiKeyAuth
+func UnlockUser(ctx *gin.Context) {
+	userId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	user, err := services.FindUserById(userId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if err := services.UnlockAccount(user); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "User unlocked successfully")
+}
+
+// @Summary      Change the role of a user
+// @Description  Give the user with the given ID a new role; the change is recorded in the audit log with the reason
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id               path  string                  true  "User ID"
+// @Param        UserRoleRequest  body  models.UserRoleRequest  true  "Role and reason"
+// @Router       /v1/user/{id}/role [put]
+// @Security     ApiKeyAuth
+func ChangeUserRole(ctx *gin.Context) {
+	var request models.UserRoleRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	user, ok := findManagedUser(ctx)
+	if !ok {
+		return
+	}
+
+	user, err := services.ChangeUserRole(ctx.MustGet("userId").(primitive.ObjectID), user, request.Role, request.Reason)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, user)
+}
+
+// @Summary      Suspend a user
+// @Description  Suspend the user with the given ID and log them out everywhere; suspended users cannot log in
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id                 path  string                    true  "User ID"
+// @Param        UserActionRequest  body  models.UserActionRequest  true  "Reason"
+// @Router       /v1/user/{id}/suspend [post]
+// @Security     ApiKeyAuth
+func SuspendUser(ctx *gin.Context) {
+	var request models.UserActionRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	user, ok := findManagedUser(ctx)
+	if !ok {
+		return
+	}
+
+	user, err := services.SuspendUser(ctx.MustGet("userId").(primitive.ObjectID), user, request.Reason)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, user)
+}
+
+// @Summary      Reactivate a user
+// @Description  Lift the suspension of the user with the given ID
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id                 path  string                    true  "User ID"
+// @Param        UserActionRequest  body  models.UserActionRequest  true  "Reason"
+// @Router       /v1/user/{id}/reactivate [post]
+// @Security     ApiKeyAuth
+func ReactivateUser(ctx *gin.Context) {
+	var request models.UserActionRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	user, ok := findManagedUser(ctx)
+	if !ok {
+		return
+	}
+
+	user, err := services.ReactivateUser(ctx.MustGet("userId").(primitive.ObjectID), user, request.Reason)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, user)
+}
+
+// @Summary      Log a user out
+// @Description  Revoke all the sessions and tokens of the user with the given ID
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id                 path  string                    true  "User ID"
+// @Param        UserActionRequest  body  models.UserActionRequest  true  "Reason"
+// @Router       /v1/user/{id}/logout [post]
+// @Security     ApiKeyAuth
+func ForceLogoutUser(ctx *gin.Context) {
+	var request models.UserActionRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	user, ok := findManagedUser(ctx)
+	if !ok {
+		return
+	}
+
+	if err := services.ForceLogoutUser(ctx.MustGet("userId").(primitive.ObjectID), user, request.Reason); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "User logged out successfully")
+}
+
+// @Summary      Get the audit log of a user
+// @Description  Get the admin actions taken on the user with the given ID, most recent first
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  utils.PaginatedResponse
+// @Param        id     path      string  true   "User ID"
+// @Param        page   query     int     false  "Page number"     default(1)
+// @Param        limit  query     int     false  "Items per page"  default(10)
+// @Router       /v1/user/{id}/audit-log [get]
+// @Security     ApiKeyAuth
+func GetUserAuditLog(ctx *gin.Context) {
+	userId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	entries, total, err := services.GetUserAuditLog(ctx.Request.Context(), userId, page, limit)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.PaginatedSuccessResponse(ctx, entries, page, limit, total)
+}
+
+// findManagedUser retrieves the user identified by the "id" path parameter.
+// If the user cannot be found, it sends a 404 error response and returns false.
+func findManagedUser(ctx *gin.Context) (*db.User, bool) {
+	userId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	user, err := services.FindUserById(userId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+
+	return user, true
+}
+
+// @Summary      Create an API key
+// @Description  Create an API key that acts as the user with the given ID, limited to the given scopes.
+// @Description  The key is only shown in this response; send it in the X-API-Key header.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id             path  string                true  "User ID"
+// @Param        APIKeyRequest  body  models.APIKeyRequest  true  "Name, scopes and optional expiry"
+// @Router       /v1/user/{id}/api-keys [post]
+// @Security     ApiKeyAuth
+func CreateAPIKey(ctx *gin.Context) {
+	var request models.APIKeyRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	user, ok := findManagedUser(ctx)
+	if !ok {
+		return
+	}
+
+	apiKey, key, err := services.CreateAPIKey(ctx.MustGet("userId").(primitive.ObjectID), user, request.Name, request.Scopes, request.ExpiresAt)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, gin.H{
+		"api_key": apiKey,
+		"key":     key,
+	})
+}
+
+// @Summary      Get the API keys of a user
+// @Description  Get the API keys of the user with the given ID, newest first, including revoked and expired ones
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id   path      string  true  "User ID"
+// @Router       /v1/user/{id}/api-keys [get]
+// @Security     ApiKeyAuth
+func GetUserAPIKeys(ctx *gin.Context) {
+	userId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	apiKeys, err := services.GetUserAPIKeys(userId)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, apiKeys)
+}
+
+// @Summary      Revoke an API key
+// @Description  Revoke an API key of the user with the given ID
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id        path      string  true  "User ID"
+// @Param        apiKeyId  path      string  true  "API key ID"
+// @Router       /v1/user/{id}/api-keys/{apiKeyId} [delete]
+// @Security     ApiKeyAuth
+func RevokeAPIKey(ctx *gin.Context) {
+	userId, _ := primitive.ObjectIDFromHex(ctx.Param("id"))
+	apiKeyId, err := primitive.ObjectIDFromHex(ctx.Param("apiKeyId"))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid api key id")
+		return
+	}
+
+	if err := services.RevokeAPIKey(userId, apiKeyId); err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "API key revoked successfully")
+}
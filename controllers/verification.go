@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"health/models"
+	"health/services"
+	"health/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Summary      Get the license verification of a doctor
+// @Description  Get the onboarding status, submitted license details and transition history of a doctor
+// @Tags         doctors
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id   path      string  true  "Doctor ID"
+// @Router       /v1/doctor/{id}/verification [get]
+// @Security     ApiKeyAuth
+func GetDoctorVerification(ctx *gin.Context) {
+	doctor, err := services.FindDoctorById(doctorIdParam(ctx))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, gin.H{
+		"doctor_id":    doctor.ID,
+		"license":      doctor.License,
+		"verification": doctor.Verification,
+	})
+}
+
+// @Summary      Submit a license for verification
+// @Description  Submit the license details and supporting documents of a doctor for review.
+// @Description  Doctors submit their own profile, admins can submit on behalf of any doctor.
+// @Tags         doctors
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id                             path      string                                true  "Doctor ID"
+// @Param        VerificationSubmissionRequest  body      models.VerificationSubmissionRequest  true  "License details"
+// @Router       /v1/doctor/me/verification [post]
+// @Router       /v1/doctor/{id}/verification [post]
+// @Security     ApiKeyAuth
+func SubmitDoctorVerification(ctx *gin.Context) {
+	doctor, err := services.FindDoctorById(doctorIdParam(ctx))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+	var request models.VerificationSubmissionRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	err = services.SubmitDoctorVerification(doctor, &request, ctx.MustGet("userId").(primitive.ObjectID))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, doctor.Verification)
+}
+
+// @Summary      Review the license verification of a doctor
+// @Description  Move a doctor to under review, approved, rejected or suspended. Rejecting or suspending requires a reason.
+// @Tags         doctors
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response
+// @Param        id                             path      string                                true  "Doctor ID"
+// @Param        VerificationTransitionRequest  body      models.VerificationTransitionRequest  true  "New status"
+// @Router       /v1/doctor/{id}/verification [put]
+// @Security     ApiKeyAuth
+func TransitionDoctorVerification(ctx *gin.Context) {
+	doctor, err := services.FindDoctorById(doctorIdParam(ctx))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+	var request models.VerificationTransitionRequest
+	_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+	err = services.TransitionDoctorVerification(doctor, request.Status, ctx.MustGet("userId").(primitive.ObjectID), request.Reason)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, doctor.Verification)
+}
@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"health/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary      Get the JSON Web Key Set
+// @Description  Get the public keys that verify the tokens issued by the API, as an RFC 7517 key set
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  services.JWKS
+// @Router       /.well-known/jwks.json [get]
+func GetJWKS(ctx *gin.Context) {
+	// the key set is published as is, verifiers expect a bare JWKS document
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, services.GetJWKS())
+}
@@ -22,7 +22,14 @@ import (
 // @description                 Type "Bearer" followed by a space and the token. Example: "<token>"
 func main() {
 	services.LoadConfig()
+	if err := services.LoadJWTKeys(); err != nil {
+		log.Fatal(err)
+	}
+	services.InitMailer()
 	services.InitMongoDB()
+	if err := services.CreateMongoIndexes(); err != nil {
+		log.Fatal(err)
+	}
 	if services.Config.UseRedis {
 		services.CheckRedisCacheConnection()
 	}
@@ -1,22 +1,33 @@
 package middlewares
 
 import (
+	"health/models"
 	db "health/models/db"
 	"health/services"
 	"health/utils"
 	"net/http"
-	"sort"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // JwtMiddleware is a middleware that verifies a JWT token from the Authorization header
-// and sets the userId, userIdHex, and role fields in the gin context.
+// and sets the token, userId, userIdHex, role and emailVerified fields in the gin context, as well as the
+// scopes of a token issued to an OAuth client, which cannot be used on first-party endpoints.
+// Revoked tokens and suspended users are rejected, and users who have to enable 2FA can only reach the /v1/auth endpoints
+// until they do.
+// Requests with an API key in the X-API-Key header instead are authenticated by apiKeyAuth.
 // If the token is invalid or the user associated with the token cannot be found,
 // it sends an unauthorized error response and aborts the request.
 func JwtMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		token := ctx.GetHeader("Authorization")
+		if key := ctx.GetHeader(services.APIKeyHeader); key != "" {
+			apiKeyAuth(ctx, key)
+			return
+		}
+
+		token := strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
 		if token == "" {
 			utils.ErrorResponse(ctx, http.StatusUnauthorized, "token is required")
 			return
@@ -33,33 +44,144 @@ func JwtMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if user.IsSuspended() {
+			utils.ErrorResponse(ctx, http.StatusForbidden, "account is suspended")
+			return
+		}
+
+		if services.IsMFARequired(user) && !strings.HasPrefix(ctx.FullPath(), "/v1/auth/") {
+			utils.ErrorResponse(ctx, http.StatusForbidden, "two-factor authentication must be enabled for this account")
+			return
+		}
+
+		if !tokenModel.Client.IsZero() {
+			if isFirstPartyPath(ctx) {
+				utils.ErrorResponse(ctx, http.StatusForbidden, "tokens issued to oauth clients cannot be used on this endpoint")
+				return
+			}
+			ctx.Set("scopes", tokenModel.Scopes)
+		}
+
+		if !tokenModel.Session.IsZero() {
+			services.TouchSession(tokenModel.Session)
+		}
+
+		ctx.Set("token", tokenModel)
 		ctx.Set("userIdHex", tokenModel.User.Hex())
 		ctx.Set("userId", tokenModel.User)
 		ctx.Set("role", user.Role)
+		ctx.Set("emailVerified", user.EmailVarified)
+		ctx.Next()
+	}
+}
+
+// apiKeyAuth authenticates a request with an API key, and sets the scopes, userId, userIdHex, role
+// and emailVerified fields in the gin context, as for the user the key belongs to. API keys cannot
+// be used on first-party endpoints, see isFirstPartyPath.
+// If the key is invalid, revoked or expired, or its user cannot be found, it sends an unauthorized
+// error response and aborts the request.
+func apiKeyAuth(ctx *gin.Context, key string) {
+	apiKey, err := services.VerifyAPIKey(key)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	user, _ := services.FindUserById(apiKey.User)
+	if user == nil {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "user not found")
+		return
+	}
+
+	if user.IsSuspended() {
+		utils.ErrorResponse(ctx, http.StatusForbidden, "account is suspended")
+		return
+	}
+
+	if isFirstPartyPath(ctx) {
+		utils.ErrorResponse(ctx, http.StatusForbidden, "api keys cannot be used on this endpoint")
+		return
+	}
+
+	ctx.Set("scopes", apiKey.Scopes)
+	ctx.Set("userIdHex", apiKey.User.Hex())
+	ctx.Set("userId", apiKey.User)
+	ctx.Set("role", user.Role)
+	ctx.Set("emailVerified", user.EmailVarified)
+	ctx.Next()
+}
+
+// isFirstPartyPath reports whether the request is for the /v1/auth or /v1/oauth endpoints, which manage
+// the sessions, credentials and app authorizations of people. API keys and tokens issued to OAuth
+// clients are refused there.
+func isFirstPartyPath(ctx *gin.Context) bool {
+	return strings.HasPrefix(ctx.FullPath(), "/v1/auth/") || strings.HasPrefix(ctx.FullPath(), "/v1/oauth/")
+}
+
+// VerifiedEmailMiddleware is a middleware that refuses users whose email address is not verified
+// when EMAIL_VERIFICATION_REQUIRED is set to routes. It must run after JwtMiddleware.
+// If the address is not verified, it sends a forbidden error response and aborts the request.
+func VerifiedEmailMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if services.IsEmailVerificationRequired(models.EmailVerificationRoutes) && !ctx.GetBool("emailVerified") {
+			utils.ErrorResponse(ctx, http.StatusForbidden, "email address is not verified")
+			return
+		}
 		ctx.Next()
 	}
 }
 
-// RoleMiddleware is a middleware function for Gin that checks the user's role
-// against a given set of allowed roles. If the user's role is not in the allowed
-// roles, it sends a forbidden error response and aborts the request. Otherwise, it
-// calls the next handler in the chain.
-func RoleMiddleware(allowedRole ...string) gin.HandlerFunc {
+// PermissionMiddleware is a middleware that checks that the role of the user grants one of the given
+// permissions. It must run after JwtMiddleware. A permission scoped with :self or :own, such as
+// users:write:self, only grants access when the user owns the resource identified by the "id" path
+// parameter, or when the route has no such parameter and the handler scopes its results to the user.
+// Requests authenticated with an API key or a token issued to an OAuth client are also limited to
+// the scopes of the key or token.
+// If no permission grants access, it sends a forbidden error response and aborts the request.
+func PermissionMiddleware(permissions ...string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		userRoleIfc, exists := ctx.Get("role")
+		role := ctx.GetString("role")
+		userId, exists := ctx.Get("userId")
 		if !exists {
-			utils.ErrorResponse(ctx, http.StatusUnauthorized, "No role found")
+			utils.ErrorResponse(ctx, http.StatusUnauthorized, "cannot get user")
 			return
 		}
+		scopes, limited := ctx.Get("scopes")
 
-		userRole := userRoleIfc.(string)
-		if sort.SearchStrings(allowedRole, userRole) < len(allowedRole) {
-			ctx.Next()
-			return
+		for _, permission := range permissions {
+			if !db.HasPermission(role, permission) {
+				continue
+			}
+			if limited && !hasScope(scopes.([]string), permission) {
+				continue
+			}
+			if !db.IsScopedPermission(permission) || ctx.Param("id") == "" {
+				ctx.Next()
+				return
+			}
+
+			resourceId, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+			if err != nil {
+				utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+				return
+			}
+			if services.IsResourceOwner(db.PermissionResource(permission), resourceId, userId.(primitive.ObjectID)) {
+				ctx.Next()
+				return
+			}
 		}
-		// If the user role is not in the allowed roles, return a forbidden error
-		// and abort the request
+
 		utils.ErrorResponse(ctx, http.StatusForbidden, "You don't have permission to access this resource")
-		ctx.Abort()
 	}
 }
+
+// hasScope reports whether the permission is one of the scopes.
+func hasScope(scopes []string, permission string) bool {
+	for _, scope := range scopes {
+		if scope == permission {
+			return true
+		}
+	}
+
+	return false
+}
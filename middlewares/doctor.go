@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"health/services"
+	"health/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DoctorProfileMiddleware is a middleware that loads the doctor profile linked to the
+// authenticated user and sets the doctorId field in the gin context.
+// It must run after JwtMiddleware. If the user has no doctor profile, it sends a
+// forbidden error response and aborts the request.
+func DoctorProfileMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId, exists := ctx.Get("userId")
+		if !exists {
+			utils.ErrorResponse(ctx, http.StatusUnauthorized, "cannot get user")
+			return
+		}
+
+		doctor, err := services.FindDoctorByUser(userId.(primitive.ObjectID))
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusForbidden, "no doctor profile is linked to this account")
+			return
+		}
+
+		ctx.Set("doctorId", doctor.ID)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,58 @@
+package validators
+
+import (
+	"health/models"
+	"health/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// AppointmentValidator is a middleware that validates the JSON body of a request
+// against the models.AppointmentRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func AppointmentValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var appointmentRequest models.AppointmentRequest
+		_ = ctx.ShouldBindBodyWith(&appointmentRequest, binding.JSON)
+		if err := appointmentRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RescheduleAppointmentValidator is a middleware that validates the JSON body of a request
+// against the models.RescheduleAppointmentRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func RescheduleAppointmentValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var rescheduleRequest models.RescheduleAppointmentRequest
+		_ = ctx.ShouldBindBodyWith(&rescheduleRequest, binding.JSON)
+		if err := rescheduleRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// CancelAppointmentValidator is a middleware that validates the JSON body of a request
+// against the models.CancelAppointmentRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func CancelAppointmentValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var cancelRequest models.CancelAppointmentRequest
+		_ = ctx.ShouldBindBodyWith(&cancelRequest, binding.JSON)
+		if err := cancelRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
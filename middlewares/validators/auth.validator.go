@@ -57,3 +57,131 @@ func RefreshValidator() gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// VerifyEmailValidator is a middleware that validates the query of a request
+// against the models.VerifyEmailRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func VerifyEmailValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var verifyEmailRequest models.VerifyEmailRequest
+		_ = ctx.ShouldBindQuery(&verifyEmailRequest)
+		if err := verifyEmailRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// OIDCCallbackValidator is a middleware that validates the query of a request
+// against the models.OIDCCallbackRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func OIDCCallbackValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var oidcCallbackRequest models.OIDCCallbackRequest
+		_ = ctx.ShouldBindQuery(&oidcCallbackRequest)
+		if err := oidcCallbackRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// ResendEmailVerificationValidator is a middleware that validates the JSON body of a request
+// against the models.ResendEmailVerificationRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func ResendEmailVerificationValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var resendRequest models.ResendEmailVerificationRequest
+		_ = ctx.ShouldBindBodyWith(&resendRequest, binding.JSON)
+		if err := resendRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// ForgotPasswordValidator is a middleware that validates the JSON body of a request
+// against the models.ForgotPasswordRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func ForgotPasswordValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var forgotPasswordRequest models.ForgotPasswordRequest
+		_ = ctx.ShouldBindBodyWith(&forgotPasswordRequest, binding.JSON)
+		if err := forgotPasswordRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// MagicLinkValidator is a middleware that validates the JSON body of a request
+// against the models.MagicLinkRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func MagicLinkValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var magicLinkRequest models.MagicLinkRequest
+		_ = ctx.ShouldBindBodyWith(&magicLinkRequest, binding.JSON)
+		if err := magicLinkRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// VerifyMagicLinkValidator is a middleware that validates the query of a request
+// against the models.VerifyMagicLinkRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func VerifyMagicLinkValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var verifyMagicLinkRequest models.VerifyMagicLinkRequest
+		_ = ctx.ShouldBindQuery(&verifyMagicLinkRequest)
+		if err := verifyMagicLinkRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// ResetPasswordValidator is a middleware that validates the JSON body of a request
+// against the models.ResetPasswordRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func ResetPasswordValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var resetPasswordRequest models.ResetPasswordRequest
+		_ = ctx.ShouldBindBodyWith(&resetPasswordRequest, binding.JSON)
+		if err := resetPasswordRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// ChangePasswordValidator is a middleware that validates the JSON body of a request
+// against the models.ChangePasswordRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func ChangePasswordValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var changePasswordRequest models.ChangePasswordRequest
+		_ = ctx.ShouldBindBodyWith(&changePasswordRequest, binding.JSON)
+		if err := changePasswordRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
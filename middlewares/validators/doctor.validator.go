@@ -0,0 +1,61 @@
+package validators
+
+import (
+	"health/models"
+	"health/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// DoctorValidator is a middleware that validates the JSON body of a request
+// against the models.DoctorRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func DoctorValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var doctorRequest models.DoctorRequest
+		_ = ctx.ShouldBindBodyWith(&doctorRequest, binding.JSON)
+		if err := doctorRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// DoctorProfileValidator is a middleware that validates the JSON body of a request
+// against the models.DoctorProfileRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func DoctorProfileValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var profileRequest models.DoctorProfileRequest
+		_ = ctx.ShouldBindBodyWith(&profileRequest, binding.JSON)
+		if err := profileRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// DoctorSearchValidator is a middleware that validates the query parameters of a request
+// against the models.DoctorSearchRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func DoctorSearchValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var searchRequest models.DoctorSearchRequest
+		if err := ctx.ShouldBindQuery(&searchRequest); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid search parameters")
+			return
+		}
+		if err := searchRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,42 @@
+package validators
+
+import (
+	"health/models"
+	"health/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// InvitationValidator is a middleware that validates the JSON body of a request
+// against the models.InvitationRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func InvitationValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var invitationRequest models.InvitationRequest
+		_ = ctx.ShouldBindBodyWith(&invitationRequest, binding.JSON)
+		if err := invitationRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// AcceptInvitationValidator is a middleware that validates the JSON body of a request
+// against the models.AcceptInvitationRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func AcceptInvitationValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var acceptInvitationRequest models.AcceptInvitationRequest
+		_ = ctx.ShouldBindBodyWith(&acceptInvitationRequest, binding.JSON)
+		if err := acceptInvitationRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,106 @@
+package validators
+
+import (
+	"health/models"
+	"health/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// ConfirmTOTPValidator is a middleware that validates the JSON body of a request
+// against the models.ConfirmTOTPRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func ConfirmTOTPValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var confirmTOTPRequest models.ConfirmTOTPRequest
+		_ = ctx.ShouldBindBodyWith(&confirmTOTPRequest, binding.JSON)
+		if err := confirmTOTPRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// MFACodeValidator is a middleware that validates the JSON body of a request
+// against the models.MFACodeRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func MFACodeValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var mfaCodeRequest models.MFACodeRequest
+		_ = ctx.ShouldBindBodyWith(&mfaCodeRequest, binding.JSON)
+		if err := mfaCodeRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// DisableMFAValidator is a middleware that validates the JSON body of a request
+// against the models.DisableMFARequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func DisableMFAValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var disableMFARequest models.DisableMFARequest
+		_ = ctx.ShouldBindBodyWith(&disableMFARequest, binding.JSON)
+		if err := disableMFARequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// VerifyMFAValidator is a middleware that validates the JSON body of a request
+// against the models.VerifyMFARequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func VerifyMFAValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var verifyMFARequest models.VerifyMFARequest
+		_ = ctx.ShouldBindBodyWith(&verifyMFARequest, binding.JSON)
+		if err := verifyMFARequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// MFAPolicyValidator is a middleware that validates the JSON body of a request
+// against the models.MFAPolicyRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func MFAPolicyValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var mfaPolicyRequest models.MFAPolicyRequest
+		_ = ctx.ShouldBindBodyWith(&mfaPolicyRequest, binding.JSON)
+		if err := mfaPolicyRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// MagicLinkPolicyValidator is a middleware that validates the JSON body of a request
+// against the models.MagicLinkPolicyRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func MagicLinkPolicyValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var magicLinkPolicyRequest models.MagicLinkPolicyRequest
+		_ = ctx.ShouldBindBodyWith(&magicLinkPolicyRequest, binding.JSON)
+		if err := magicLinkPolicyRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
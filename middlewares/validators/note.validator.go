@@ -0,0 +1,26 @@
+package validators
+
+import (
+	"health/models"
+	"health/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// NoteValidator is a middleware that validates the JSON body of a request
+// against the models.NoteRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func NoteValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var noteRequest models.NoteRequest
+		_ = ctx.ShouldBindBodyWith(&noteRequest, binding.JSON)
+		if err := noteRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
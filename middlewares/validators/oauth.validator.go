@@ -0,0 +1,58 @@
+package validators
+
+import (
+	"health/models"
+	"health/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// OAuthClientValidator is a middleware that validates the JSON body of a request
+// against the models.OAuthClientRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func OAuthClientValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var oauthClientRequest models.OAuthClientRequest
+		_ = ctx.ShouldBindBodyWith(&oauthClientRequest, binding.JSON)
+		if err := oauthClientRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// OAuthAuthorizeQueryValidator is a middleware that validates the query parameters of a request
+// against the models.OAuthAuthorizeRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func OAuthAuthorizeQueryValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var oauthAuthorizeRequest models.OAuthAuthorizeRequest
+		_ = ctx.ShouldBindQuery(&oauthAuthorizeRequest)
+		if err := oauthAuthorizeRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// OAuthAuthorizeValidator is a middleware that validates the JSON body of a request
+// against the models.OAuthAuthorizeRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func OAuthAuthorizeValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var oauthAuthorizeRequest models.OAuthAuthorizeRequest
+		_ = ctx.ShouldBindBodyWith(&oauthAuthorizeRequest, binding.JSON)
+		if err := oauthAuthorizeRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package validators
+
+import (
+	"health/models"
+	db "health/models/db"
+	"health/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// ScheduleExceptionValidator is a middleware that validates the JSON body of a request
+// against the models.ScheduleExceptionRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func ScheduleExceptionValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var exceptionRequest models.ScheduleExceptionRequest
+		_ = ctx.ShouldBindBodyWith(&exceptionRequest, binding.JSON)
+		if err := exceptionRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// HolidayValidator is a middleware that validates the JSON body of a request
+// against the models.HolidayRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func HolidayValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var holidayRequest models.HolidayRequest
+		_ = ctx.ShouldBindBodyWith(&holidayRequest, binding.JSON)
+		if err := holidayRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// ScheduleValidator is a middleware that validates the JSON body of a request
+// against the db.WeeklySchedule struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func ScheduleValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var schedule db.WeeklySchedule
+		_ = ctx.ShouldBindBodyWith(&schedule, binding.JSON)
+		if err := schedule.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
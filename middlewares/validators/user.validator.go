@@ -0,0 +1,58 @@
+package validators
+
+import (
+	"health/models"
+	"health/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// UserRoleValidator is a middleware that validates the JSON body of a request
+// against the models.UserRoleRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func UserRoleValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var userRoleRequest models.UserRoleRequest
+		_ = ctx.ShouldBindBodyWith(&userRoleRequest, binding.JSON)
+		if err := userRoleRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// UserActionValidator is a middleware that validates the JSON body of a request
+// against the models.UserActionRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func UserActionValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var userActionRequest models.UserActionRequest
+		_ = ctx.ShouldBindBodyWith(&userActionRequest, binding.JSON)
+		if err := userActionRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// APIKeyValidator is a middleware that validates the JSON body of a request
+// against the models.APIKeyRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func APIKeyValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var apiKeyRequest models.APIKeyRequest
+		_ = ctx.ShouldBindBodyWith(&apiKeyRequest, binding.JSON)
+		if err := apiKeyRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
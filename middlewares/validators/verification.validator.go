@@ -0,0 +1,42 @@
+package validators
+
+import (
+	"health/models"
+	"health/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// VerificationSubmissionValidator is a middleware that validates the JSON body of a request
+// against the models.VerificationSubmissionRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func VerificationSubmissionValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var submissionRequest models.VerificationSubmissionRequest
+		_ = ctx.ShouldBindBodyWith(&submissionRequest, binding.JSON)
+		if err := submissionRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// VerificationTransitionValidator is a middleware that validates the JSON body of a request
+// against the models.VerificationTransitionRequest struct. If the validation fails, it sends
+// a 400 error response with the error message and aborts the request. If the
+// validation succeeds, it calls the next handler in the chain.
+func VerificationTransitionValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var transitionRequest models.VerificationTransitionRequest
+		_ = ctx.ShouldBindBodyWith(&transitionRequest, binding.JSON)
+		if err := transitionRequest.Validate(); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	db "health/models/db"
+
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/go-ozzo/ozzo-validation/is"
 )
@@ -13,12 +15,67 @@ type EnvConfig struct {
 	UseRedis                   bool   `mapstructure:"USE_REDIS"`
 	RedisDefaultAddr           string `mapstructure:"REDIS_DEFAULT_ADDR"`
 	JWTSecretKey               string `mapstructure:"JWT_SECRET"`
+	JWTKeysDir                 string `mapstructure:"JWT_KEYS_DIR"`
+	JWTActiveKeyID             string `mapstructure:"JWT_ACTIVE_KID"`
 	JWTAccessExpirationMinutes int    `mapstructure:"JWT_ACCESS_EXPIRATION_MINUTES"`
 	JWTRefreshExpirationDays   int    `mapstructure:"JWT_REFRESH_EXPIRATION_DAYS"`
+	AppointmentSlotMinutes     int    `mapstructure:"APPOINTMENT_SLOT_MINUTES"`
+	AppURL                     string `mapstructure:"APP_URL"`
+	MailTransport              string `mapstructure:"MAIL_TRANSPORT"`
+	MailFrom                   string `mapstructure:"MAIL_FROM"`
+	MailFileDir                string `mapstructure:"MAIL_FILE_DIR"`
+	SMTPAddr                   string `mapstructure:"SMTP_ADDR"`
+	SMTPUsername               string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword               string `mapstructure:"SMTP_PASSWORD"`
+	EmailVerificationRequired  string `mapstructure:"EMAIL_VERIFICATION_REQUIRED"`
+	EmailVerificationHours     int    `mapstructure:"EMAIL_VERIFICATION_EXPIRATION_HOURS"`
+	PasswordResetMinutes       int    `mapstructure:"PASSWORD_RESET_EXPIRATION_MINUTES"`
+	PasswordResetMaxRequests   int    `mapstructure:"PASSWORD_RESET_MAX_REQUESTS"`
+	PasswordResetMaxPerIP      int    `mapstructure:"PASSWORD_RESET_MAX_REQUESTS_PER_IP"`
+	MFAIssuer                  string `mapstructure:"MFA_ISSUER"`
+	LoginMaxAttempts           int    `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginMaxAttemptsPerIP      int    `mapstructure:"LOGIN_MAX_ATTEMPTS_PER_IP"`
+	LoginAttemptWindowMinutes  int    `mapstructure:"LOGIN_ATTEMPT_WINDOW_MINUTES"`
+	LoginLockoutMinutes        int    `mapstructure:"LOGIN_LOCKOUT_MINUTES"`
+	LoginMaxLockoutMinutes     int    `mapstructure:"LOGIN_MAX_LOCKOUT_MINUTES"`
+	InvitationHours            int    `mapstructure:"INVITATION_EXPIRATION_HOURS"`
+	MagicLinkMinutes           int    `mapstructure:"MAGIC_LINK_EXPIRATION_MINUTES"`
+	MagicLinkMaxRequests       int    `mapstructure:"MAGIC_LINK_MAX_REQUESTS"`
+	MagicLinkMaxRequestsPerIP  int    `mapstructure:"MAGIC_LINK_MAX_REQUESTS_PER_IP"`
+	OIDCIssuer                 string `mapstructure:"OIDC_ISSUER"`
+	OIDCClientID               string `mapstructure:"OIDC_CLIENT_ID"`
+	OIDCClientSecret           string `mapstructure:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL            string `mapstructure:"OIDC_REDIRECT_URL"`
+	OIDCScopes                 string `mapstructure:"OIDC_SCOPES"`
+	OIDCDefaultRole            string `mapstructure:"OIDC_DEFAULT_ROLE"`
 	Mode                       string `mapstructure:"MODE"` // Added closing quotation mark
 }
 
+const (
+	EmailVerificationNone   = "none"
+	EmailVerificationLogin  = "login"
+	EmailVerificationRoutes = "routes"
+)
+
 func (config *EnvConfig) Validate() error {
+	// without a key set tokens are signed with the secret, with one it only verifies older tokens
+	secretRules := []validation.Rule{}
+	activeKeyRules := []validation.Rule{}
+	if config.JWTKeysDir == "" {
+		secretRules = append(secretRules, validation.Required)
+	} else {
+		activeKeyRules = append(activeKeyRules, validation.Required)
+	}
+	// OIDC login is enabled by setting an issuer, which then needs a client
+	oidcClientRules := []validation.Rule{}
+	if config.OIDCIssuer != "" {
+		oidcClientRules = append(oidcClientRules, validation.Required)
+	}
+	smtpAddrRules := []validation.Rule{is.DialString}
+	if config.MailTransport == "smtp" {
+		smtpAddrRules = append(smtpAddrRules, validation.Required)
+	}
+
 	return validation.ValidateStruct(config,
 		validation.Field(&config.ServerPort, is.Port),
 		validation.Field(&config.ServerAddr, validation.Required),
@@ -29,10 +86,39 @@ func (config *EnvConfig) Validate() error {
 		validation.Field(&config.UseRedis, validation.In(true, false)),
 		validation.Field(&config.RedisDefaultAddr),
 
-		validation.Field(&config.JWTSecretKey, validation.Required),
+		validation.Field(&config.JWTSecretKey, secretRules...),
+		validation.Field(&config.JWTActiveKeyID, activeKeyRules...),
 		validation.Field(&config.JWTAccessExpirationMinutes, validation.Required),
 		validation.Field(&config.JWTRefreshExpirationDays, validation.Required),
 
+		validation.Field(&config.AppointmentSlotMinutes, validation.Required, validation.Min(5), validation.Max(240)),
+
+		validation.Field(&config.AppURL, validation.Required, is.URL),
+		validation.Field(&config.MailTransport, validation.In("log", "file", "smtp")),
+		validation.Field(&config.MailFrom, validation.Required, is.Email),
+		validation.Field(&config.SMTPAddr, smtpAddrRules...),
+		validation.Field(&config.EmailVerificationRequired, validation.In(EmailVerificationNone, EmailVerificationLogin, EmailVerificationRoutes)),
+		validation.Field(&config.EmailVerificationHours, validation.Required, validation.Min(1)),
+		validation.Field(&config.PasswordResetMinutes, validation.Required, validation.Min(5), validation.Max(1440)),
+		validation.Field(&config.PasswordResetMaxRequests, validation.Required, validation.Min(1)),
+		validation.Field(&config.PasswordResetMaxPerIP, validation.Required, validation.Min(1)),
+		validation.Field(&config.MFAIssuer, validation.Required, validation.Length(1, 64)),
+
+		validation.Field(&config.LoginMaxAttempts, validation.Required, validation.Min(1)),
+		validation.Field(&config.LoginMaxAttemptsPerIP, validation.Required, validation.Min(1)),
+		validation.Field(&config.LoginAttemptWindowMinutes, validation.Required, validation.Min(1)),
+		validation.Field(&config.LoginLockoutMinutes, validation.Required, validation.Min(1)),
+		validation.Field(&config.LoginMaxLockoutMinutes, validation.Required, validation.Min(config.LoginLockoutMinutes)),
+		validation.Field(&config.InvitationHours, validation.Required, validation.Min(1), validation.Max(720)),
+		validation.Field(&config.MagicLinkMinutes, validation.Required, validation.Min(1), validation.Max(60)),
+		validation.Field(&config.MagicLinkMaxRequests, validation.Required, validation.Min(1)),
+		validation.Field(&config.MagicLinkMaxRequestsPerIP, validation.Required, validation.Min(1)),
+
+		validation.Field(&config.OIDCIssuer, is.URL),
+		validation.Field(&config.OIDCClientID, oidcClientRules...),
+		validation.Field(&config.OIDCRedirectURL, append(oidcClientRules, is.URL)...),
+		validation.Field(&config.OIDCDefaultRole, validation.In(inValues(db.Roles)...)),
+
 		validation.Field(&config.Mode, validation.In("debug", "release")),
 	)
 }
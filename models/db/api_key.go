@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKey lets a service call the API on behalf of a user, usually a dedicated service account,
+// without logging in. Only the SHA-256 hash of the key is stored; the key itself is shown once.
+type APIKey struct {
+	mgm.DefaultModel `bson:",inline"`
+	User             primitive.ObjectID `json:"user" bson:"user"`
+	Name             string             `json:"name" bson:"name"`
+	Prefix           string             `json:"prefix" bson:"prefix"` // start of the key, to tell keys apart
+	Hash             string             `json:"-" bson:"hash"`
+	Scopes           []string           `json:"scopes" bson:"scopes"` // permissions the key is limited to
+	CreatedBy        primitive.ObjectID `json:"created_by" bson:"created_by"`
+	ExpiresAt        *time.Time         `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	LastUsedAt       *time.Time         `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	RevokedAt        *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// NewAPIKey creates a new APIKey of the user, created by the given admin, from the prefix and hash of the key.
+func NewAPIKey(userId primitive.ObjectID, name string, prefix string, hash string, scopes []string, createdBy primitive.ObjectID, expiresAt *time.Time) *APIKey {
+	return &APIKey{
+		User:      userId,
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      hash,
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// IsActive reports whether the key is neither revoked nor expired.
+func (model *APIKey) IsActive() bool {
+	return model.RevokedAt == nil && (model.ExpiresAt == nil || model.ExpiresAt.After(time.Now()))
+}
+
+// CollectionName returns the name of the collection that stores APIKey documents.
+func (model *APIKey) CollectionName() string {
+	return "api_keys"
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	AppointmentStatusBooked    = "booked"
+	AppointmentStatusCancelled = "cancelled"
+)
+
+type Appointment struct {
+	mgm.DefaultModel `bson:",inline"`
+	Doctor           primitive.ObjectID `json:"doctor" bson:"doctor"`
+	Patient          primitive.ObjectID `json:"patient" bson:"patient"`
+	StartsAt         time.Time          `json:"starts_at" bson:"starts_at"`
+	EndsAt           time.Time          `json:"ends_at" bson:"ends_at"`
+	Slots            []time.Time        `json:"-" bson:"slots"`
+	Status           string             `json:"status" bson:"status"`
+	Reason           string             `json:"reason" bson:"reason"`
+	CancelReason     string             `json:"cancel_reason,omitempty" bson:"cancel_reason,omitempty"`
+	CancelledBy      primitive.ObjectID `json:"cancelled_by,omitempty" bson:"cancelled_by,omitempty"`
+}
+
+// NewAppointment creates a new booked Appointment of the given patient with the given doctor.
+// The slots are the starts of every booking grid cell the appointment covers, and are what
+// the unique index on the appointments collection uses to reject double bookings.
+func NewAppointment(doctorId primitive.ObjectID, patientId primitive.ObjectID, startsAt time.Time, endsAt time.Time, slots []time.Time, reason string) *Appointment {
+	return &Appointment{
+		Doctor:   doctorId,
+		Patient:  patientId,
+		StartsAt: startsAt,
+		EndsAt:   endsAt,
+		Slots:    slots,
+		Status:   AppointmentStatusBooked,
+		Reason:   reason,
+	}
+}
+
+// CollectionName returns the name of the collection that stores Appointment documents.
+func (model *Appointment) CollectionName() string {
+	return "appointments"
+}
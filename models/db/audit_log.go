@@ -0,0 +1,40 @@
+package models
+
+import (
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	AuditActionRoleChanged     = "role_changed"
+	AuditActionUserSuspended   = "user_suspended"
+	AuditActionUserReactivated = "user_reactivated"
+	AuditActionUserLoggedOut   = "user_logged_out"
+	AuditActionUserInvited     = "user_invited"
+)
+
+// AuditLog records an action an admin took on the account of a user, and why.
+type AuditLog struct {
+	mgm.DefaultModel `bson:",inline"`
+	Actor            primitive.ObjectID `json:"actor" bson:"actor"`
+	User             primitive.ObjectID `json:"user" bson:"user"`
+	Action           string             `json:"action" bson:"action"`
+	Reason           string             `json:"reason" bson:"reason"`
+	Details          string             `json:"details,omitempty" bson:"details,omitempty"`
+}
+
+// NewAuditLog creates a new AuditLog of the given action taken by the actor on the account of the user.
+func NewAuditLog(actorId primitive.ObjectID, userId primitive.ObjectID, action string, reason string, details string) *AuditLog {
+	return &AuditLog{
+		Actor:   actorId,
+		User:    userId,
+		Action:  action,
+		Reason:  reason,
+		Details: details,
+	}
+}
+
+// CollectionName returns the name of the collection that stores AuditLog documents.
+func (model *AuditLog) CollectionName() string {
+	return "audit_logs"
+}
@@ -1,38 +1,108 @@
 package models
 
 import (
+	"context"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Specializations holds the valid values of Doctor.Specialization.
+var Specializations = []string{
+	"Allergy and Immunology",
+	"Anesthesiology",
+	"Cardiology",
+	"Dermatology",
+	"Endocrinology",
+	"Family Medicine",
+	"Gastroenterology",
+	"General Surgery",
+	"Internal Medicine",
+	"Nephrology",
+	"Neurology",
+	"Obstetrics and Gynecology",
+	"Oncology",
+	"Ophthalmology",
+	"Orthopedics",
+	"Otolaryngology",
+	"Pediatrics",
+	"Psychiatry",
+	"Pulmonology",
+	"Radiology",
+	"Rheumatology",
+	"Urology",
+}
+
 type Doctor struct {
 	mgm.DefaultModel `bson:",inline"`
-	Name             string   `bson:"name"`
-	Specialization   string   `bson:"specialization"`
-	Phone            string   `bson:"phone"`
-	Experience       string   `bson:"experience"`
-	Location         string   `bson:"location"`
-	License          string   `bson:"license"`
-	WorkHours        string   `bson:"work_hours"`
-	Availability     bool     `bson:"availability"`
-	WorkDays         []string `bson:"work_days"`
-	WorkTime         []string `bson:"work_time"`
-	WorkTimeEnd      []string `bson:"work_time_end"`
-}
-
-func NewDoctor(name string, specialization string, phone string, experience string, location string, license string, workHours string, availability bool, workDays []string, workTime []string, workTimeEnd []string) *Doctor {
+	User             primitive.ObjectID `json:"user,omitempty" bson:"user,omitempty"`
+	Name             string             `json:"name" bson:"name"`
+	Specialization   string             `json:"specialization" bson:"specialization"`
+	Phone            string             `json:"phone" bson:"phone"`
+	ExperienceYears  int                `json:"experience_years" bson:"experience_years"`
+	Location         string             `json:"location" bson:"location"`
+	Coordinates      *GeoPoint          `json:"coordinates,omitempty" bson:"coordinates,omitempty"`
+	License          string             `json:"license" bson:"license"`
+	Availability     bool               `json:"availability" bson:"availability"`
+	Schedule         WeeklySchedule     `json:"schedule" bson:"schedule"`
+	Verification     Verification       `json:"verification" bson:"verification"`
+	SearchName       string             `json:"-" bson:"search_name"`
+	SearchLocation   string             `json:"-" bson:"search_location"`
+}
+
+func NewDoctor(name string, specialization string, phone string, experienceYears int, location string, license string, availability bool, schedule WeeklySchedule) *Doctor {
 	return &Doctor{
-		Name:           name,
-		Specialization: specialization,
-		Phone:          phone,
-		Experience:     experience,
-		Location:       location,
-		License:        license,
-		WorkHours:      workHours,
-		Availability:   availability,
-		WorkDays:       workDays,
-		WorkTime:       workTime,
-		WorkTimeEnd:    workTimeEnd,
+		Name:            name,
+		Specialization:  specialization,
+		Phone:           phone,
+		ExperienceYears: experienceYears,
+		Location:        location,
+		License:         license,
+		Availability:    availability,
+		Schedule:        schedule,
+		Verification:    Verification{Status: VerificationPending},
+	}
+}
+
+// NearbyDoctor is a doctor found by a geospatial search, along with its distance in kilometers
+// from the searched point.
+type NearbyDoctor struct {
+	Doctor   `bson:",inline"`
+	Distance float64 `json:"distance" bson:"distance"`
+}
+
+// IsApproved reports whether the license of the doctor has been verified, which is required
+// to be listed and to accept bookings.
+func (model *Doctor) IsApproved() bool {
+	return model.Verification.CurrentStatus() == VerificationApproved
+}
+
+// Validate validates the Doctor struct.
+// It checks that the weekly schedule and the optional coordinates are valid.
+func (model *Doctor) Validate() error {
+	return validation.ValidateStruct(model,
+		validation.Field(&model.Schedule),
+		validation.Field(&model.Coordinates),
+	)
+}
+
+// Saving validates the doctor before it is created or updated, so an inconsistent
+// schedule never reaches the database. It also refreshes the normalized search fields.
+func (model *Doctor) Saving(ctx context.Context) error {
+	if err := model.DefaultModel.Saving(); err != nil {
+		return err
 	}
+	model.SearchName = NormalizeSearch(model.Name)
+	model.SearchLocation = NormalizeSearch(model.Location)
+	return model.Validate()
+}
+
+// NormalizeSearch lowercases the value and collapses its whitespace, so that searches can
+// match it case-insensitively with an anchored, index-backed prefix query.
+func NormalizeSearch(value string) string {
+	return strings.Join(strings.Fields(strings.ToLower(value)), " ")
 }
 
 func (model *Doctor) CollectionName() string {
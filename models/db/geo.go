@@ -0,0 +1,50 @@
+package models
+
+import (
+	"errors"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// GeoPointType is the GeoJSON type of GeoPoint.
+const GeoPointType = "Point"
+
+// GeoPoint is a GeoJSON point, stored as is so that it can be indexed with a 2dsphere index.
+// Coordinates holds the longitude first and the latitude second, as GeoJSON requires.
+type GeoPoint struct {
+	Type        string    `json:"type" bson:"type"`
+	Coordinates []float64 `json:"coordinates" bson:"coordinates"`
+}
+
+// NewGeoPoint creates a new GeoPoint at the given latitude and longitude.
+func NewGeoPoint(latitude float64, longitude float64) *GeoPoint {
+	return &GeoPoint{
+		Type:        GeoPointType,
+		Coordinates: []float64{longitude, latitude},
+	}
+}
+
+// Validate validates the GeoPoint struct.
+// It checks that the point is a GeoJSON Point with a valid longitude and latitude.
+func (a GeoPoint) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Type, validation.Required, validation.In(GeoPointType)),
+		validation.Field(&a.Coordinates, validation.Required, validation.By(lngLat)),
+	)
+}
+
+// lngLat checks that the value holds a longitude between -180 and 180 followed by
+// a latitude between -90 and 90.
+func lngLat(value interface{}) error {
+	coordinates, _ := value.([]float64)
+	if len(coordinates) != 2 {
+		return errors.New("must be a longitude and a latitude")
+	}
+	if coordinates[0] < -180 || coordinates[0] > 180 {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	if coordinates[1] < -90 || coordinates[1] > 90 {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	return nil
+}
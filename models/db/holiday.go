@@ -0,0 +1,28 @@
+package models
+
+import (
+	"github.com/kamva/mgm/v3"
+)
+
+// HolidayDateLayout is the layout of Holiday.Date.
+const HolidayDateLayout = "2006-01-02"
+
+// Holiday is a clinic-wide day off. It blocks the whole date in the timezone of every doctor.
+type Holiday struct {
+	mgm.DefaultModel `bson:",inline"`
+	Date             string `json:"date" bson:"date"`
+	Name             string `json:"name" bson:"name"`
+}
+
+// NewHoliday creates a new Holiday on the given "2006-01-02" formatted date.
+func NewHoliday(date string, name string) *Holiday {
+	return &Holiday{
+		Date: date,
+		Name: name,
+	}
+}
+
+// CollectionName returns the name of the collection that stores Holiday documents.
+func (model *Holiday) CollectionName() string {
+	return "holidays"
+}
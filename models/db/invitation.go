@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Invitation is an account an admin invited a staff member to create, with a pre-assigned role and
+// optionally linked to a doctor profile. The invitee accepts it with the signed token sent by email.
+type Invitation struct {
+	mgm.DefaultModel `bson:",inline"`
+	Email            string             `json:"email" bson:"email"`
+	Name             string             `json:"name" bson:"name"`
+	Role             string             `json:"role" bson:"role"`
+	Doctor           primitive.ObjectID `json:"doctor,omitempty" bson:"doctor,omitempty"` // doctor profile the account is linked to
+	InvitedBy        primitive.ObjectID `json:"invited_by" bson:"invited_by"`
+	TokenID          string             `json:"-" bson:"token_id"` // id of the last token sent, earlier tokens stop working on resend
+	SentAt           time.Time          `json:"sent_at" bson:"sent_at"`
+	ExpiresAt        time.Time          `json:"expires_at" bson:"expires_at"`
+	AcceptedAt       *time.Time         `json:"accepted_at,omitempty" bson:"accepted_at,omitempty"`
+	User             primitive.ObjectID `json:"user,omitempty" bson:"user,omitempty"` // account created when the invitation was accepted
+	RevokedAt        *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// NewInvitation creates a new Invitation of the given email address, name and role, sent by the admin with the given ObjectID.
+func NewInvitation(email string, name string, role string, invitedBy primitive.ObjectID) *Invitation {
+	return &Invitation{
+		Email:     email,
+		Name:      name,
+		Role:      role,
+		InvitedBy: invitedBy,
+	}
+}
+
+// IsPending reports whether the invitation has been neither accepted nor revoked.
+// A pending invitation may have expired, in which case it can be resent.
+func (model *Invitation) IsPending() bool {
+	return model.AcceptedAt == nil && model.RevokedAt == nil
+}
+
+// CollectionName returns the name of the collection that stores Invitation documents.
+func (model *Invitation) CollectionName() string {
+	return "invitations"
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	OAuthGrantAuthorizationCode = "authorization_code"
+	OAuthGrantClientCredentials = "client_credentials"
+	OAuthGrantRefreshToken      = "refresh_token"
+)
+
+// OAuthGrants holds the valid values of OAuthClient.GrantTypes.
+var OAuthGrants = []string{OAuthGrantAuthorizationCode, OAuthGrantClientCredentials, OAuthGrantRefreshToken}
+
+// OAuthClient is a third-party app registered by an admin to act on behalf of users with the
+// authorization code flow, or on its own with the client credentials flow. Its ID is the OAuth
+// client_id. Only the SHA-256 hash of the secret of a confidential client is stored.
+type OAuthClient struct {
+	mgm.DefaultModel `bson:",inline"`
+	Name             string             `json:"name" bson:"name"`
+	SecretHash       string             `json:"-" bson:"secret_hash,omitempty"`
+	Confidential     bool               `json:"confidential" bson:"confidential"`
+	RedirectURIs     []string           `json:"redirect_uris" bson:"redirect_uris"`
+	GrantTypes       []string           `json:"grant_types" bson:"grant_types"`
+	Scopes           []string           `json:"scopes" bson:"scopes"`                 // permissions the client may ask for
+	User             primitive.ObjectID `json:"user,omitempty" bson:"user,omitempty"` // service account the client credentials flow acts as
+	CreatedBy        primitive.ObjectID `json:"created_by" bson:"created_by"`
+	RevokedAt        *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// AllowsGrant reports whether the client may use the grant type.
+func (model *OAuthClient) AllowsGrant(grantType string) bool {
+	return containsString(model.GrantTypes, grantType)
+}
+
+// AllowsScope reports whether the client may ask for the scope.
+func (model *OAuthClient) AllowsScope(scope string) bool {
+	return containsString(model.Scopes, scope)
+}
+
+// AllowsRedirectURI reports whether the redirect URI is one the client registered, compared exactly.
+func (model *OAuthClient) AllowsRedirectURI(redirectURI string) bool {
+	return containsString(model.RedirectURIs, redirectURI)
+}
+
+// CollectionName returns the name of the collection that stores OAuthClient documents.
+func (model *OAuthClient) CollectionName() string {
+	return "oauth_clients"
+}
+
+// OAuthAuthorizationCode is the single-use code a user approved a client with, to be exchanged for
+// tokens along with the PKCE code verifier. Only the SHA-256 hash of the code is stored.
+type OAuthAuthorizationCode struct {
+	mgm.DefaultModel `bson:",inline"`
+	Client           primitive.ObjectID `json:"client" bson:"client"`
+	User             primitive.ObjectID `json:"user" bson:"user"`
+	CodeHash         string             `json:"-" bson:"code_hash"`
+	RedirectURI      string             `json:"redirect_uri" bson:"redirect_uri"`
+	Scopes           []string           `json:"scopes" bson:"scopes"`
+	CodeChallenge    string             `json:"-" bson:"code_challenge"` // S256 PKCE challenge
+	ExpiresAt        time.Time          `json:"expires_at" bson:"expires_at"`
+	UsedAt           *time.Time         `json:"used_at,omitempty" bson:"used_at,omitempty"`
+	Session          primitive.ObjectID `json:"-" bson:"session,omitempty"` // session the code was exchanged for
+}
+
+// CollectionName returns the name of the collection that stores OAuthAuthorizationCode documents.
+func (model *OAuthAuthorizationCode) CollectionName() string {
+	return "oauth_authorization_codes"
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
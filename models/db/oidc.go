@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+)
+
+// OIDCLoginState is an OpenID Connect login that was sent to the provider and waits for its callback.
+// Only the SHA-256 hash of the state is stored; the nonce and the PKCE verifier are checked at the callback.
+type OIDCLoginState struct {
+	mgm.DefaultModel `bson:",inline"`
+	StateHash        string    `json:"-" bson:"state_hash"`
+	Nonce            string    `json:"-" bson:"nonce"`
+	CodeVerifier     string    `json:"-" bson:"code_verifier"`
+	ExpiresAt        time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// CollectionName returns the name of the collection that stores OIDCLoginState documents.
+func (model *OIDCLoginState) CollectionName() string {
+	return "oidc_login_states"
+}
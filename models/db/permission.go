@@ -0,0 +1,85 @@
+package models
+
+import "strings"
+
+// Permissions are named <resource>:<action>, optionally followed by a scope. A permission scoped
+// with :self or :own only grants access to resources the user owns.
+const (
+	PermissionUsersRead          = "users:read"
+	PermissionUsersReadSelf      = "users:read:self"
+	PermissionUsersWrite         = "users:write"
+	PermissionUsersWriteSelf     = "users:write:self"
+	PermissionUsersManage        = "users:manage"
+	PermissionAPIKeysManage      = "api_keys:manage"
+	PermissionOAuthClientsManage = "oauth_clients:manage"
+	PermissionSessionsReadOwn    = "sessions:read:own"
+	PermissionSessionsWriteOwn   = "sessions:write:own"
+	PermissionSecurityManage     = "security:manage"
+
+	PermissionDoctorsRead      = "doctors:read"
+	PermissionDoctorsManage    = "doctors:manage"
+	PermissionDoctorsWriteSelf = "doctors:write:self"
+
+	PermissionAppointmentsRead     = "appointments:read"
+	PermissionAppointmentsUpcoming = "appointments:upcoming"
+	PermissionAppointmentsReadOwn  = "appointments:read:own"
+	PermissionAppointmentsWrite    = "appointments:write"
+	PermissionAppointmentsWriteOwn = "appointments:write:own"
+
+	PermissionNotesReadOwn  = "notes:read:own"
+	PermissionNotesWriteOwn = "notes:write:own"
+
+	PermissionHolidaysRead   = "holidays:read"
+	PermissionHolidaysManage = "holidays:manage"
+)
+
+// Permissions holds every permission, all of which are granted to admins.
+var Permissions = []string{
+	PermissionUsersRead, PermissionUsersReadSelf, PermissionUsersWrite, PermissionUsersWriteSelf, PermissionUsersManage, PermissionAPIKeysManage, PermissionOAuthClientsManage,
+	PermissionSessionsReadOwn, PermissionSessionsWriteOwn, PermissionSecurityManage,
+	PermissionDoctorsRead, PermissionDoctorsManage, PermissionDoctorsWriteSelf,
+	PermissionAppointmentsRead, PermissionAppointmentsUpcoming, PermissionAppointmentsReadOwn, PermissionAppointmentsWrite, PermissionAppointmentsWriteOwn,
+	PermissionNotesReadOwn, PermissionNotesWriteOwn,
+	PermissionHolidaysRead, PermissionHolidaysManage,
+}
+
+// accountPermissions are granted to every role, to manage one's own account, notes and appointments.
+var accountPermissions = []string{
+	PermissionUsersReadSelf, PermissionUsersWriteSelf,
+	PermissionSessionsReadOwn, PermissionSessionsWriteOwn,
+	PermissionDoctorsRead,
+	PermissionAppointmentsReadOwn, PermissionAppointmentsWriteOwn,
+	PermissionNotesReadOwn, PermissionNotesWriteOwn,
+	PermissionHolidaysRead,
+}
+
+// RolePermissions maps each role to the permissions it grants.
+var RolePermissions = map[string][]string{
+	RoleUser:     accountPermissions,
+	RolePharmacy: accountPermissions,
+	RoleLab:      accountPermissions,
+	RoleDoctor:   append(append([]string{}, accountPermissions...), PermissionDoctorsWriteSelf, PermissionAppointmentsUpcoming),
+	RoleAdmin:    Permissions,
+}
+
+// HasPermission reports whether the role grants the permission.
+func HasPermission(role string, permission string) bool {
+	for _, granted := range RolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PermissionResource returns the resource a permission applies to, such as users for users:read.
+func PermissionResource(permission string) string {
+	resource, _, _ := strings.Cut(permission, ":")
+	return resource
+}
+
+// IsScopedPermission reports whether the permission only applies to resources the user owns.
+func IsScopedPermission(permission string) bool {
+	return strings.HasSuffix(permission, ":self") || strings.HasSuffix(permission, ":own")
+}
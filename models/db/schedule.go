@@ -0,0 +1,142 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// ClockLayout is the layout of the start and end times of a WorkInterval.
+const ClockLayout = "15:04"
+
+var clockRule = validation.Match(regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)).Error("must be a HH:MM time")
+
+// Weekdays holds the valid values of DaySchedule.Day, in the order of time.Weekday.
+var Weekdays = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// WorkInterval is a range of working time within a day, as "15:04" formatted local clock times.
+type WorkInterval struct {
+	Start string `json:"start" bson:"start"`
+	End   string `json:"end" bson:"end"`
+}
+
+// DaySchedule holds the working intervals of one day of the week.
+type DaySchedule struct {
+	Day       string         `json:"day" bson:"day"`
+	Intervals []WorkInterval `json:"intervals" bson:"intervals"`
+}
+
+// WeeklySchedule is the recurring working week of a doctor.
+// The clock times of its intervals are local to the IANA Timezone.
+type WeeklySchedule struct {
+	Timezone string        `json:"timezone" bson:"timezone"`
+	Days     []DaySchedule `json:"days" bson:"days"`
+}
+
+// Validate validates the WorkInterval struct.
+// It checks that both times are zero padded "15:04" formatted times and that the interval ends after it starts.
+func (a WorkInterval) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Start, validation.Required, clockRule),
+		validation.Field(&a.End, validation.Required, clockRule, validation.By(func(value interface{}) error {
+			// "15:04" formatted times sort the same way as strings and as clock times
+			if value.(string) <= a.Start {
+				return errors.New("must be after start")
+			}
+			return nil
+		})),
+	)
+}
+
+// Validate validates the DaySchedule struct.
+// It checks that the day is a lowercase weekday name and that its intervals are valid,
+// sorted and do not overlap.
+func (a DaySchedule) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Day, validation.Required, validation.In(weekdayValues()...)),
+		validation.Field(&a.Intervals, validation.Required, validation.By(sortedIntervals)),
+	)
+}
+
+// Validate validates the WeeklySchedule struct.
+// It checks that the timezone is a known IANA timezone and that every day is valid
+// and appears at most once.
+func (a WeeklySchedule) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Timezone, validation.Required, validation.By(ianaTimezone)),
+		validation.Field(&a.Days, validation.By(uniqueDays)),
+	)
+}
+
+// Location returns the timezone of the schedule, or UTC if it cannot be loaded.
+func (a WeeklySchedule) Location() *time.Location {
+	location, err := time.LoadLocation(a.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// IntervalsOn returns the working intervals of the given day of the week.
+func (a WeeklySchedule) IntervalsOn(weekday time.Weekday) []WorkInterval {
+	for _, day := range a.Days {
+		if day.Day == Weekdays[weekday] {
+			return day.Intervals
+		}
+	}
+	return nil
+}
+
+// ParseWeekday returns the lowercase weekday name of the given day name, accepting
+// any capitalization and three letter abbreviations such as "Mon".
+func ParseWeekday(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, weekday := range Weekdays {
+		if name == weekday || (len(name) == 3 && strings.HasPrefix(weekday, name)) {
+			return weekday, nil
+		}
+	}
+	return "", fmt.Errorf("invalid weekday: %q", name)
+}
+
+func weekdayValues() []interface{} {
+	values := make([]interface{}, len(Weekdays))
+	for i, weekday := range Weekdays {
+		values[i] = weekday
+	}
+	return values
+}
+
+func ianaTimezone(value interface{}) error {
+	timezone, _ := value.(string)
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "Local" {
+		return errors.New("must be a valid IANA timezone")
+	}
+	return nil
+}
+
+func uniqueDays(value interface{}) error {
+	days, _ := value.([]DaySchedule)
+	seen := map[string]bool{}
+	for _, day := range days {
+		if seen[day.Day] {
+			return fmt.Errorf("%s appears more than once", day.Day)
+		}
+		seen[day.Day] = true
+	}
+	return nil
+}
+
+func sortedIntervals(value interface{}) error {
+	intervals, _ := value.([]WorkInterval)
+	for i := 1; i < len(intervals); i++ {
+		if intervals[i].Start < intervals[i-1].End {
+			return errors.New("must be sorted and must not overlap")
+		}
+	}
+	return nil
+}
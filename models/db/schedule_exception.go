@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ScheduleExceptionTimeOff    = "time_off"
+	ScheduleExceptionExtraHours = "extra_hours"
+)
+
+// ScheduleException is a one-off change to the weekly schedule of a doctor: either time off
+// that blocks otherwise working time, or extra hours that add working time.
+type ScheduleException struct {
+	mgm.DefaultModel `bson:",inline"`
+	Doctor           primitive.ObjectID `json:"doctor" bson:"doctor"`
+	Type             string             `json:"type" bson:"type"`
+	StartsAt         time.Time          `json:"starts_at" bson:"starts_at"`
+	EndsAt           time.Time          `json:"ends_at" bson:"ends_at"`
+	Reason           string             `json:"reason" bson:"reason"`
+	CreatedBy        primitive.ObjectID `json:"created_by" bson:"created_by"`
+}
+
+// NewScheduleException creates a new ScheduleException of the given type for the doctor.
+func NewScheduleException(doctorId primitive.ObjectID, exceptionType string, startsAt time.Time, endsAt time.Time, reason string, createdBy primitive.ObjectID) *ScheduleException {
+	return &ScheduleException{
+		Doctor:    doctorId,
+		Type:      exceptionType,
+		StartsAt:  startsAt,
+		EndsAt:    endsAt,
+		Reason:    reason,
+		CreatedBy: createdBy,
+	}
+}
+
+// CollectionName returns the name of the collection that stores ScheduleException documents.
+func (model *ScheduleException) CollectionName() string {
+	return "schedule_exceptions"
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	SecurityEventRefreshTokenReuse = "refresh_token_reuse"
+	SecurityEventMFADisabled       = "mfa_disabled"
+	SecurityEventRecoveryCodeUsed  = "recovery_code_used"
+	SecurityEventAccountLocked     = "account_locked"
+)
+
+// SecurityEvent records a suspicious event on the account of a user, such as a replayed refresh token.
+type SecurityEvent struct {
+	mgm.DefaultModel `bson:",inline"`
+	User             primitive.ObjectID `json:"user" bson:"user"`
+	Type             string             `json:"type" bson:"type"`
+	IP               string             `json:"ip" bson:"ip"`
+	UserAgent        string             `json:"user_agent" bson:"user_agent"`
+	Details          string             `json:"details" bson:"details"`
+}
+
+// NewSecurityEvent creates a new SecurityEvent of the given type on the account of the given user,
+// caused by the client with the given IP address and user agent.
+func NewSecurityEvent(userId primitive.ObjectID, eventType string, ip string, userAgent string, details string) *SecurityEvent {
+	return &SecurityEvent{
+		User:      userId,
+		Type:      eventType,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   details,
+	}
+}
+
+// CollectionName returns the name of the collection that stores SecurityEvent documents.
+func (model *SecurityEvent) CollectionName() string {
+	return "security_events"
+}
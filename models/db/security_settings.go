@@ -0,0 +1,17 @@
+package models
+
+import (
+	"github.com/kamva/mgm/v3"
+)
+
+// SecuritySettings holds the security policy administrators manage at runtime. A single document is stored.
+type SecuritySettings struct {
+	mgm.DefaultModel `bson:",inline"`
+	MFARequiredRoles []string `json:"mfa_required_roles" bson:"mfa_required_roles"`
+	MagicLinkRoles   []string `json:"magic_link_roles" bson:"magic_link_roles"` // roles whose users may log in with a magic link
+}
+
+// CollectionName returns the name of the collection that stores the SecuritySettings document.
+func (model *SecuritySettings) CollectionName() string {
+	return "security_settings"
+}
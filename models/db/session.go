@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session groups the access and refresh tokens issued to one client of a user, from login until
+// the session is revoked or its last refresh token expires.
+type Session struct {
+	mgm.DefaultModel `bson:",inline"`
+	User             primitive.ObjectID `json:"user" bson:"user"`
+	UserAgent        string             `json:"user_agent" bson:"user_agent"`
+	IP               string             `json:"ip" bson:"ip"`
+	LastUsedAt       time.Time          `json:"last_used_at" bson:"last_used_at"`
+	ExpiresAt        time.Time          `json:"expires_at" bson:"expires_at"`
+	RevokedAt        *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	Client           primitive.ObjectID `json:"client,omitempty" bson:"client,omitempty"` // OAuth client the session was authorized for
+	Scopes           []string           `json:"scopes,omitempty" bson:"scopes,omitempty"` // permissions the tokens of an OAuth session are limited to
+	Current          bool               `json:"current" bson:"-"`                         // set on the session of the request when listing
+}
+
+// NewSession creates a new Session of the given user for the client with the given user agent and IP address.
+func NewSession(userId primitive.ObjectID, userAgent string, ip string) *Session {
+	return &Session{
+		User:       userId,
+		UserAgent:  userAgent,
+		IP:         ip,
+		LastUsedAt: time.Now().UTC(),
+	}
+}
+
+// IsActive reports whether the session has neither been revoked nor expired.
+func (model *Session) IsActive() bool {
+	return model.RevokedAt == nil && time.Now().Before(model.ExpiresAt)
+}
+
+// CollectionName returns the name of the collection that stores Session documents.
+func (model *Session) CollectionName() string {
+	return "sessions"
+}
@@ -9,8 +9,13 @@ import (
 )
 
 const (
-	TokenTypeAccess  = "access"
-	TokenTypeRefresh = "refresh"
+	TokenTypeAccess            = "access"
+	TokenTypeRefresh           = "refresh"
+	TokenTypeEmailVerification = "email_verification"
+	TokenTypePasswordReset     = "password_reset"
+	TokenTypeMFAPending        = "mfa_pending"
+	TokenTypeMagicLink         = "magic_link"
+	TokenTypeInvitation        = "invitation" // signed for an Invitation, not stored as a Token
 )
 
 type Token struct {
@@ -20,6 +25,13 @@ type Token struct {
 	Type             string             `json:"type" bson:"type"`
 	ExpriesAt        time.Time          `json:"expries_at" bson:"expries_at"`
 	BlackListed      bool               `json:"blacklisted" bson:"blacklisted"`
+	Session          primitive.ObjectID `json:"-" bson:"session,omitempty"`
+	Parent           primitive.ObjectID `json:"-" bson:"parent,omitempty"`     // the refresh token a refresh token was rotated from
+	RotatedAt        *time.Time         `json:"-" bson:"rotated_at,omitempty"` // when a refresh token was exchanged for a new pair
+	Attempts         int                `json:"-" bson:"attempts,omitempty"`   // failed codes presented with an mfa pending token
+	Client           primitive.ObjectID `json:"-" bson:"client,omitempty"`     // OAuth client the token was issued to
+	Scopes           []string           `json:"-" bson:"scopes,omitempty"`     // permissions a token issued to an OAuth client is limited to
+	Device           string             `json:"-" bson:"device,omitempty"`     // SHA-256 hash of the secret of the device a magic link was requested from
 }
 
 // GetResponseJson returns a gin.H representation of the token that is safe for transmission over the network.
@@ -15,21 +15,46 @@ const (
 	RoleLab      = "lab"
 )
 
+// Roles holds the valid values of User.Role.
+var Roles = []string{RoleUser, RoleAdmin, RoleDoctor, RolePharmacy, RoleLab}
+
 type User struct {
 	mgm.DefaultModel `bson:",inline"`
-	Email            string    `json:"email" bson:"email"`
-	Password         string    `json:"-" bson:"password"`
-	Name             string    `json:"name" bson:"name"`
-	Role             string    `json:"role" bson:"role"`
-	EmailVarified    bool      `json:"mail_verified" bson:"email_verified"`
-	CreatedAt        time.Time `json:"created_at" bson:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at" bson:"updated_at"`
+	Email            string     `json:"email" bson:"email"`
+	Password         string     `json:"-" bson:"password"`
+	Name             string     `json:"name" bson:"name"`
+	Role             string     `json:"role" bson:"role"`
+	EmailVarified    bool       `json:"mail_verified" bson:"email_verified"`
+	MFA              UserMFA    `json:"mfa" bson:"mfa"`
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty" bson:"suspended_at,omitempty"`
+	OIDC             *UserOIDC  `json:"oidc,omitempty" bson:"oidc,omitempty"`
+	CreatedAt        time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" bson:"updated_at"`
+}
+
+// UserMFA holds the TOTP two-factor authentication state of a user. The secret is set at enrollment
+// and only takes effect once a first code confirms it.
+type UserMFA struct {
+	Enabled       bool       `json:"enabled" bson:"enabled"`
+	EnabledAt     *time.Time `json:"enabled_at,omitempty" bson:"enabled_at,omitempty"`
+	Secret        string     `json:"-" bson:"secret,omitempty"`
+	RecoveryCodes []string   `json:"-" bson:"recovery_codes,omitempty"` // SHA-256 hashes of the unused recovery codes
+	LastUsedStep  int64      `json:"-" bson:"last_used_step,omitempty"` // TOTP time step of the last accepted code, codes cannot be replayed
+}
+
+// UserOIDC links a user to the account at an OpenID Connect provider they log in with.
+type UserOIDC struct {
+	Issuer   string    `json:"issuer" bson:"issuer"`
+	Subject  string    `json:"subject" bson:"subject"`
+	LinkedAt time.Time `json:"linked_at" bson:"linked_at"`
 }
 
 type UserClaims struct {
 	jwt.RegisteredClaims
-	Email string `json:"email"`
-	Type  string `json:"type"`
+	Email    string `json:"email"`
+	Type     string `json:"type"`
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
 }
 
 func NewUser(email string, password string, name string, role string) *User {
@@ -44,6 +69,11 @@ func NewUser(email string, password string, name string, role string) *User {
 	}
 }
 
+// IsSuspended reports whether an admin suspended the account, which refuses its logins and tokens.
+func (model *User) IsSuspended() bool {
+	return model.SuspendedAt != nil
+}
+
 func (model *User) CollectionName() string {
 	return "users"
 }
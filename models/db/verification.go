@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	VerificationPending     = "pending"
+	VerificationSubmitted   = "submitted"
+	VerificationUnderReview = "under_review"
+	VerificationApproved    = "approved"
+	VerificationRejected    = "rejected"
+	VerificationSuspended   = "suspended"
+)
+
+// VerificationTransitions maps each onboarding status to the statuses it can move to.
+// Doctors move their profile to submitted; every other move is made by an admin.
+var VerificationTransitions = map[string][]string{
+	VerificationPending:     {VerificationSubmitted},
+	VerificationSubmitted:   {VerificationUnderReview, VerificationRejected},
+	VerificationUnderReview: {VerificationApproved, VerificationRejected},
+	VerificationApproved:    {VerificationSuspended},
+	VerificationRejected:    {VerificationSubmitted},
+	VerificationSuspended:   {VerificationApproved, VerificationUnderReview},
+}
+
+// VerificationDocument is a supporting document of a license submission, such as a scan of the license.
+type VerificationDocument struct {
+	Name string `json:"name" bson:"name"`
+	Url  string `json:"url" bson:"url"`
+}
+
+// VerificationEvent records one move of a doctor between onboarding statuses.
+type VerificationEvent struct {
+	From   string             `json:"from" bson:"from"`
+	To     string             `json:"to" bson:"to"`
+	Actor  primitive.ObjectID `json:"actor" bson:"actor"`
+	Reason string             `json:"reason,omitempty" bson:"reason,omitempty"`
+	At     time.Time          `json:"at" bson:"at"`
+}
+
+// Verification holds the license verification state of a doctor and the history of how it got there.
+type Verification struct {
+	Status           string                 `json:"status" bson:"status"`
+	IssuingAuthority string                 `json:"issuing_authority,omitempty" bson:"issuing_authority,omitempty"`
+	LicenseExpiresAt time.Time              `json:"license_expires_at,omitempty" bson:"license_expires_at,omitempty"`
+	Documents        []VerificationDocument `json:"documents,omitempty" bson:"documents,omitempty"`
+	Reason           string                 `json:"reason,omitempty" bson:"reason,omitempty"`
+	History          []VerificationEvent    `json:"history,omitempty" bson:"history,omitempty"`
+}
+
+// Validate validates the VerificationDocument struct.
+// It checks that the name is filled in and that the url is a valid URL.
+func (a VerificationDocument) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Name, validation.Required, validation.Length(1, 100)),
+		validation.Field(&a.Url, validation.Required, is.URL),
+	)
+}
+
+// CurrentStatus returns the onboarding status, treating doctors stored before onboarding
+// existed as pending.
+func (a Verification) CurrentStatus() string {
+	if a.Status == "" {
+		return VerificationPending
+	}
+	return a.Status
+}
+
+// CanTransition reports whether the verification can move from its current status to the given one.
+func (a Verification) CanTransition(to string) bool {
+	for _, allowed := range VerificationTransitions[a.CurrentStatus()] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
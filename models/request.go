@@ -1,7 +1,9 @@
 package models
 
 import (
+	db "health/models/db"
 	"regexp"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/go-ozzo/ozzo-validation/is"
@@ -61,6 +63,407 @@ func (a RefreshRequest) Validate() error {
 	)
 }
 
+type VerifyEmailRequest struct {
+	Token string `form:"token"`
+}
+
+// Validate validates the VerifyEmailRequest struct.
+// It checks that the token is required and does not contain any whitespace.
+func (a VerifyEmailRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(
+			&a.Token,
+			validation.Required,
+			validation.Match(regexp.MustCompile(`^\S+$`)).Error("cannot contain whitespaces"),
+		),
+	)
+}
+
+type OIDCCallbackRequest struct {
+	Code  string `form:"code"`
+	State string `form:"state"`
+	Error string `form:"error"`
+}
+
+// Validate validates the OIDCCallbackRequest struct.
+// It checks that the code and state are present, unless the provider reported an error.
+func (a OIDCCallbackRequest) Validate() error {
+	rules := []validation.Rule{}
+	if a.Error == "" {
+		rules = append(rules, validation.Required)
+	}
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Code, rules...),
+		validation.Field(&a.State, rules...),
+	)
+}
+
+type ResendEmailVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// Validate validates the ResendEmailVerificationRequest struct.
+// It checks that the email is a valid email address.
+func (a ResendEmailVerificationRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Email, validation.Required, is.Email),
+	)
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// Validate validates the ForgotPasswordRequest struct.
+// It checks that the email is a valid email address.
+func (a ForgotPasswordRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Email, validation.Required, is.Email),
+	)
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// Validate validates the MagicLinkRequest struct.
+// It checks that the email is a valid email address.
+func (a MagicLinkRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Email, validation.Required, is.Email),
+	)
+}
+
+type VerifyMagicLinkRequest struct {
+	Token string `form:"token"`
+}
+
+// Validate validates the VerifyMagicLinkRequest struct.
+// It checks that the token is required and does not contain any whitespace.
+func (a VerifyMagicLinkRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(
+			&a.Token,
+			validation.Required,
+			validation.Match(regexp.MustCompile(`^\S+$`)).Error("cannot contain whitespaces"),
+		),
+	)
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// Validate validates the ResetPasswordRequest struct.
+// It checks that the token is required and does not contain any whitespace,
+// and that the new password follows the password rules.
+func (a ResetPasswordRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(
+			&a.Token,
+			validation.Required,
+			validation.Match(regexp.MustCompile(`^\S+$`)).Error("cannot contain whitespaces"),
+		),
+		validation.Field(&a.Password, passwordRule...),
+	)
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Password        string `json:"password"`
+}
+
+// Validate validates the ChangePasswordRequest struct.
+// It checks that the current password is filled in, that the new password follows
+// the password rules and that it differs from the current one.
+func (a ChangePasswordRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.CurrentPassword, validation.Required),
+		validation.Field(&a.Password, append(passwordRule, validation.NotIn(a.CurrentPassword).Error("must differ from the current password"))...),
+	)
+}
+
+var (
+	totpCodeRule = validation.Match(regexp.MustCompile(`^\d{6}$`)).Error("must be a 6 digit code")
+	mfaCodeRule  = validation.Match(regexp.MustCompile(`^(\d{6}|[a-zA-Z2-7]{4}-[a-zA-Z2-7]{4})$`)).Error("must be a 6 digit code or a recovery code")
+)
+
+type ConfirmTOTPRequest struct {
+	Code string `json:"code"`
+}
+
+// Validate validates the ConfirmTOTPRequest struct.
+// It checks that the code is a 6 digit code.
+func (a ConfirmTOTPRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Code, validation.Required, totpCodeRule),
+	)
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+// Validate validates the MFACodeRequest struct.
+// It checks that the code is a 6 digit code or a recovery code.
+func (a MFACodeRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Code, validation.Required, mfaCodeRule),
+	)
+}
+
+type DisableMFARequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// Validate validates the DisableMFARequest struct.
+// It checks that the password is filled in and that the code is a 6 digit code or a recovery code.
+func (a DisableMFARequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Password, validation.Required),
+		validation.Field(&a.Code, validation.Required, mfaCodeRule),
+	)
+}
+
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+// Validate validates the VerifyMFARequest struct.
+// It checks that the mfa token does not contain any whitespace and that the code is a 6 digit code or a recovery code.
+func (a VerifyMFARequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(
+			&a.MFAToken,
+			validation.Required,
+			validation.Match(regexp.MustCompile(`^\S+$`)).Error("cannot contain whitespaces"),
+		),
+		validation.Field(&a.Code, validation.Required, mfaCodeRule),
+	)
+}
+
+type MFAPolicyRequest struct {
+	MFARequiredRoles []string `json:"mfa_required_roles"`
+}
+
+// Validate validates the MFAPolicyRequest struct.
+// It checks that every role is one of db.Roles.
+func (a MFAPolicyRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.MFARequiredRoles, validation.NotNil, validation.Each(validation.In(inValues(db.Roles)...))),
+	)
+}
+
+type InvitationRequest struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	DoctorId string `json:"doctor_id"`
+}
+
+// Validate validates the InvitationRequest struct.
+// It checks that the email is a valid email address, that the name is given, that the role is one of
+// db.Roles, and that a doctor profile is only linked to an invited doctor.
+func (a InvitationRequest) Validate() error {
+	doctorRules := []validation.Rule{is.MongoID}
+	if a.Role != db.RoleDoctor {
+		doctorRules = append(doctorRules, validation.In().Error("can only be set for the doctor role"))
+	}
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Email, validation.Required, is.Email),
+		validation.Field(&a.Name, validation.Required, validation.Length(3, 64)),
+		validation.Field(&a.Role, validation.Required, validation.In(inValues(db.Roles)...)),
+		validation.Field(&a.DoctorId, doctorRules...),
+	)
+}
+
+type AcceptInvitationRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// Validate validates the AcceptInvitationRequest struct.
+// It checks that the token is required and does not contain any whitespace,
+// and that the password follows the password rules.
+func (a AcceptInvitationRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(
+			&a.Token,
+			validation.Required,
+			validation.Match(regexp.MustCompile(`^\S+$`)).Error("cannot contain whitespaces"),
+		),
+		validation.Field(&a.Password, passwordRule...),
+	)
+}
+
+type MagicLinkPolicyRequest struct {
+	MagicLinkRoles []string `json:"magic_link_roles"`
+}
+
+// Validate validates the MagicLinkPolicyRequest struct.
+// It checks that every role is one of db.Roles.
+func (a MagicLinkPolicyRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.MagicLinkRoles, validation.NotNil, validation.Each(validation.In(inValues(db.Roles)...))),
+	)
+}
+
+type UserRoleRequest struct {
+	Role   string `json:"role"`
+	Reason string `json:"reason"`
+}
+
+// Validate validates the UserRoleRequest struct.
+// It checks that the role is one of db.Roles and that a reason is given.
+func (a UserRoleRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Role, validation.Required, validation.In(inValues(db.Roles)...)),
+		validation.Field(&a.Reason, validation.Required, validation.Length(3, 500)),
+	)
+}
+
+type UserActionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Validate validates the UserActionRequest struct.
+// It checks that a reason is given.
+func (a UserActionRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Reason, validation.Required, validation.Length(3, 500)),
+	)
+}
+
+type APIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Validate validates the APIKeyRequest struct.
+// It checks that the name has a length between 3 and 64, that at least one scope is given and
+// every scope is one of db.Permissions, and that the optional expiry is in the future.
+func (a APIKeyRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Name, validation.Required, validation.Length(3, 64)),
+		validation.Field(&a.Scopes, validation.Required, validation.Each(validation.In(inValues(db.Permissions)...))),
+		validation.Field(&a.ExpiresAt, validation.Min(time.Now()).Error("must be in the future")),
+	)
+}
+
+type OAuthClientRequest struct {
+	Name         string   `json:"name"`
+	Confidential bool     `json:"confidential"`
+	RedirectURIs []string `json:"redirect_uris"`
+	GrantTypes   []string `json:"grant_types"`
+	Scopes       []string `json:"scopes"`
+	User         string   `json:"user"`
+}
+
+// Validate validates the OAuthClientRequest struct.
+// It checks that the name has a length between 3 and 64, that the grant types are among db.OAuthGrants
+// and the scopes among db.Permissions. Clients of the authorization code flow need redirect URIs, and
+// clients of the client credentials flow must be confidential and act as a user.
+func (a OAuthClientRequest) Validate() error {
+	redirectRules := []validation.Rule{validation.Each(validation.Required, is.URL)}
+	confidentialRules := []validation.Rule{}
+	userRules := []validation.Rule{is.MongoID}
+	for _, grantType := range a.GrantTypes {
+		switch grantType {
+		case db.OAuthGrantAuthorizationCode:
+			redirectRules = append(redirectRules, validation.Required)
+		case db.OAuthGrantClientCredentials:
+			confidentialRules = append(confidentialRules, validation.Required.Error("must be true for the client credentials flow"))
+			userRules = append(userRules, validation.Required)
+		}
+	}
+
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Name, validation.Required, validation.Length(3, 64)),
+		validation.Field(&a.Confidential, confidentialRules...),
+		validation.Field(&a.RedirectURIs, redirectRules...),
+		validation.Field(&a.GrantTypes, validation.Required, validation.Each(validation.In(inValues(db.OAuthGrants)...))),
+		validation.Field(&a.Scopes, validation.Required, validation.Each(validation.In(inValues(db.Permissions)...))),
+		validation.Field(&a.User, userRules...),
+	)
+}
+
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+	Approve             bool   `form:"-" json:"approve"`
+}
+
+// Validate validates the OAuthAuthorizeRequest struct.
+// It checks that the response type is code, that the client, redirect URI and scope are given,
+// and that a PKCE code challenge is given with the S256 method.
+func (a OAuthAuthorizeRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.ResponseType, validation.Required, validation.In("code")),
+		validation.Field(&a.ClientID, validation.Required, is.MongoID),
+		validation.Field(&a.RedirectURI, validation.Required),
+		validation.Field(&a.Scope, validation.Required),
+		validation.Field(&a.State, validation.Length(0, 512)),
+		validation.Field(&a.CodeChallenge, validation.Required, validation.Match(regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)).Error("must be a base64url encoded SHA-256 hash")),
+		validation.Field(&a.CodeChallengeMethod, validation.Required, validation.In("S256")),
+	)
+}
+
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// Validate validates the OAuthTokenRequest struct.
+// It checks that the grant type is one of db.OAuthGrants and that the parameters it needs are given.
+func (a OAuthTokenRequest) Validate() error {
+	codeRules := []validation.Rule{}
+	refreshTokenRules := []validation.Rule{}
+	switch a.GrantType {
+	case db.OAuthGrantAuthorizationCode:
+		codeRules = append(codeRules, validation.Required)
+	case db.OAuthGrantRefreshToken:
+		refreshTokenRules = append(refreshTokenRules, validation.Required)
+	}
+
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.GrantType, validation.Required, validation.In(inValues(db.OAuthGrants)...)),
+		validation.Field(&a.Code, codeRules...),
+		validation.Field(&a.RedirectURI, codeRules...),
+		validation.Field(&a.CodeVerifier, codeRules...),
+		validation.Field(&a.RefreshToken, refreshTokenRules...),
+	)
+}
+
+type OAuthTokenHintRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// Validate validates the OAuthTokenHintRequest struct, used by the introspection and revocation endpoints.
+// It checks that the token is given.
+func (a OAuthTokenHintRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Token, validation.Required),
+	)
+}
+
 type NoteRequest struct {
 	Title   string `json:"title"`
 	Content string `json:"content"`
@@ -74,3 +477,235 @@ func (a NoteRequest) Validate() error {
 		validation.Field(&a.Content, validation.Required),
 	)
 }
+
+type AppointmentRequest struct {
+	DoctorId string    `json:"doctor_id"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Reason   string    `json:"reason"`
+}
+
+// Validate validates the AppointmentRequest struct.
+// It checks that the doctor id is a valid id, that both times are set,
+// that the appointment ends after it starts and that the reason is at most 500 characters.
+func (a AppointmentRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.DoctorId, validation.Required, is.MongoID),
+		validation.Field(&a.StartsAt, validation.Required),
+		validation.Field(&a.EndsAt, validation.Required, validation.Min(a.StartsAt).Exclusive().Error("must be after starts_at")),
+		validation.Field(&a.Reason, validation.Length(0, 500)),
+	)
+}
+
+type RescheduleAppointmentRequest struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// Validate validates the RescheduleAppointmentRequest struct.
+// It checks that both times are set and that the appointment ends after it starts.
+func (a RescheduleAppointmentRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.StartsAt, validation.Required),
+		validation.Field(&a.EndsAt, validation.Required, validation.Min(a.StartsAt).Exclusive().Error("must be after starts_at")),
+	)
+}
+
+type CancelAppointmentRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Validate validates the CancelAppointmentRequest struct.
+// It checks that the reason is at most 500 characters.
+func (a CancelAppointmentRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Reason, validation.Length(0, 500)),
+	)
+}
+
+type ScheduleExceptionRequest struct {
+	Type     string    `json:"type"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Reason   string    `json:"reason"`
+}
+
+// Validate validates the ScheduleExceptionRequest struct.
+// It checks that the type is either time off or extra hours, that both times are set,
+// that the exception ends after it starts and that the reason is at most 500 characters.
+func (a ScheduleExceptionRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Type, validation.Required, validation.In(db.ScheduleExceptionTimeOff, db.ScheduleExceptionExtraHours)),
+		validation.Field(&a.StartsAt, validation.Required),
+		validation.Field(&a.EndsAt, validation.Required, validation.Min(a.StartsAt).Exclusive().Error("must be after starts_at")),
+		validation.Field(&a.Reason, validation.Length(0, 500)),
+	)
+}
+
+type HolidayRequest struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+// Validate validates the HolidayRequest struct.
+// It checks that the date is a YYYY-MM-DD date and that the name is between 3 and 100 characters.
+func (a HolidayRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Date, validation.Required, validation.Date(db.HolidayDateLayout)),
+		validation.Field(&a.Name, validation.Required, validation.Length(3, 100)),
+	)
+}
+
+var (
+	licenseRule = validation.Match(regexp.MustCompile(`^[A-Z]{2,5}-?\d{3,10}$`)).Error("must be 2 to 5 capital letters followed by 3 to 10 digits")
+	phoneRule   = validation.Match(regexp.MustCompile(`^\+[1-9]\d{7,14}$`)).Error("must be an E.164 phone number such as +14155550123")
+)
+
+// inValues converts the allowed string values to the arguments of validation.In.
+func inValues(values []string) []interface{} {
+	converted := make([]interface{}, len(values))
+	for i, value := range values {
+		converted[i] = value
+	}
+	return converted
+}
+
+type DoctorRequest struct {
+	UserId          string            `json:"user_id"`
+	Name            string            `json:"name"`
+	Specialization  string            `json:"specialization"`
+	Phone           string            `json:"phone"`
+	ExperienceYears int               `json:"experience_years"`
+	Location        string            `json:"location"`
+	Coordinates     *db.GeoPoint      `json:"coordinates"`
+	License         string            `json:"license"`
+	Availability    bool              `json:"availability"`
+	Schedule        db.WeeklySchedule `json:"schedule"`
+}
+
+// Validate validates the DoctorRequest struct.
+// It checks that the optional user id is a valid id, that the name and location are filled in, that the license and phone number are well formed,
+// that the specialization is one of db.Specializations, that the optional coordinates are a valid point and that the weekly schedule is consistent.
+func (a DoctorRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.UserId, is.MongoID),
+		validation.Field(&a.Name, validation.Required, validation.Length(3, 64)),
+		validation.Field(&a.Specialization, validation.Required, validation.In(inValues(db.Specializations)...).Error("must be a known specialization")),
+		validation.Field(&a.Phone, validation.Required, phoneRule),
+		validation.Field(&a.ExperienceYears, validation.Min(0), validation.Max(80)),
+		validation.Field(&a.Location, validation.Required, validation.Length(2, 100)),
+		validation.Field(&a.Coordinates),
+		validation.Field(&a.License, validation.Required, licenseRule),
+		validation.Field(&a.Schedule),
+	)
+}
+
+type DoctorProfileRequest struct {
+	Phone           string       `json:"phone"`
+	ExperienceYears int          `json:"experience_years"`
+	Location        string       `json:"location"`
+	Coordinates     *db.GeoPoint `json:"coordinates"`
+	Availability    *bool        `json:"availability"`
+}
+
+// Validate validates the DoctorProfileRequest struct.
+// It checks that the phone number is well formed, that the location is filled in
+// and that the optional coordinates are a valid point.
+func (a DoctorProfileRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Phone, validation.Required, phoneRule),
+		validation.Field(&a.ExperienceYears, validation.Min(0), validation.Max(80)),
+		validation.Field(&a.Location, validation.Required, validation.Length(2, 100)),
+		validation.Field(&a.Coordinates),
+	)
+}
+
+type VerificationSubmissionRequest struct {
+	License          string                    `json:"license"`
+	IssuingAuthority string                    `json:"issuing_authority"`
+	LicenseExpiresAt time.Time                 `json:"license_expires_at"`
+	Documents        []db.VerificationDocument `json:"documents"`
+}
+
+// Validate validates the VerificationSubmissionRequest struct.
+// It checks that the license is well formed, that the issuing authority is filled in,
+// that the license has not expired and that between 1 and 10 valid documents are attached.
+func (a VerificationSubmissionRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.License, validation.Required, licenseRule),
+		validation.Field(&a.IssuingAuthority, validation.Required, validation.Length(2, 100)),
+		validation.Field(&a.LicenseExpiresAt, validation.Required, validation.Min(time.Now()).Error("license must not be expired")),
+		validation.Field(&a.Documents, validation.Required, validation.Length(1, 10)),
+	)
+}
+
+type VerificationTransitionRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+// Validate validates the VerificationTransitionRequest struct.
+// It checks that the status is one an admin can move a doctor to,
+// and that a reason is given when the doctor is rejected or suspended.
+func (a VerificationTransitionRequest) Validate() error {
+	reasonRules := []validation.Rule{validation.Length(0, 500)}
+	if a.Status == db.VerificationRejected || a.Status == db.VerificationSuspended {
+		reasonRules = append(reasonRules, validation.Required)
+	}
+
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Status, validation.Required, validation.In(db.VerificationUnderReview, db.VerificationApproved, db.VerificationRejected, db.VerificationSuspended)),
+		validation.Field(&a.Reason, reasonRules...),
+	)
+}
+
+type DoctorSearchRequest struct {
+	Name           string   `form:"name"`
+	Specialization string   `form:"specialization"`
+	Location       string   `form:"location"`
+	Available      string   `form:"available"`
+	Day            string   `form:"day"`
+	MinExperience  int      `form:"min_experience"`
+	Sort           string   `form:"sort"`
+	Order          string   `form:"order"`
+	Lat            *float64 `form:"lat"`
+	Lng            *float64 `form:"lng"`
+	Radius         float64  `form:"radius"`
+}
+
+// MaxSearchRadius is the largest radius in kilometers of a "doctors near me" search.
+const MaxSearchRadius = 500
+
+// IsNearby reports whether the search looks for doctors around a point.
+func (a DoctorSearchRequest) IsNearby() bool {
+	return a.Lat != nil && a.Lng != nil
+}
+
+// Validate validates the DoctorSearchRequest struct.
+// It checks that the specialization and day are known values, that available is a boolean,
+// that the minimum experience is not negative and that the sort field and order are supported.
+// A nearby search needs both lat and lng within range; the radius is in kilometers and
+// can only be given along with them.
+func (a DoctorSearchRequest) Validate() error {
+	latRules := []validation.Rule{validation.Min(-90.0), validation.Max(90.0)}
+	lngRules := []validation.Rule{validation.Min(-180.0), validation.Max(180.0)}
+	radiusRules := []validation.Rule{validation.Min(0.0), validation.Max(float64(MaxSearchRadius))}
+	if a.Lat != nil || a.Lng != nil || a.Radius != 0 {
+		latRules = append(latRules, validation.NotNil)
+		lngRules = append(lngRules, validation.NotNil)
+	}
+
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Name, validation.Length(0, 64)),
+		validation.Field(&a.Specialization, validation.In(inValues(db.Specializations)...).Error("must be a known specialization")),
+		validation.Field(&a.Location, validation.Length(0, 100)),
+		validation.Field(&a.Available, validation.In("true", "false")),
+		validation.Field(&a.Day, validation.In(inValues(db.Weekdays)...)),
+		validation.Field(&a.MinExperience, validation.Min(0)),
+		validation.Field(&a.Sort, validation.In("name", "experience")),
+		validation.Field(&a.Order, validation.In("asc", "desc")),
+		validation.Field(&a.Lat, latRules...),
+		validation.Field(&a.Lng, lngRules...),
+		validation.Field(&a.Radius, radiusRules...),
+	)
+}
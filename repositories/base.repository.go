@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"reflect"
 
 	"github.com/kamva/mgm/v3"
 	"go.mongodb.org/mongo-driver/bson"
@@ -18,6 +19,16 @@ func BaseRepository[T Model](model T) GenericRepository[T] {
 	}
 }
 
+// newModel returns a new zero value model to decode into. Models are pointer types,
+// so the zero value of T is a nil pointer that cannot be decoded into.
+func newModel[T Model]() T {
+	var model T
+	if modelType := reflect.TypeOf(model); modelType != nil && modelType.Kind() == reflect.Pointer {
+		return reflect.New(modelType.Elem()).Interface().(T)
+	}
+	return model
+}
+
 func (r *newBaseRepository[T]) FindAll() ([]T, error) {
 	var results []T
 	err := r.collection.SimpleFind(&results, bson.M{})
@@ -46,7 +57,7 @@ func (r *newBaseRepository[T]) FindAllPaginated(page int, limit int) ([]T, int64
 }
 
 func (r *newBaseRepository[T]) FindByID(id string) (T, error) {
-	var result T
+	result := newModel[T]()
 	err := r.collection.FindByID(id, result)
 	return result, err
 }
@@ -60,7 +71,7 @@ func (r *newBaseRepository[T]) Update(entity T) error {
 }
 
 func (r *newBaseRepository[T]) Delete(id string) error {
-	var result T
+	result := newModel[T]()
 	err := r.collection.FindByID(id, result)
 	if err != nil {
 		return err
@@ -1,4 +1,41 @@
 package repositories
 
-// DoctorRepository can be implemented using BaseRepository if needed
-// For now, doctor operations are handled directly in services/doctor.service.go
+import (
+	db "health/models/db"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DoctorRepository interface {
+	GenericRepository[*db.Doctor]
+	FindByLicense(license string) (*db.Doctor, error)
+	FindByUser(userId primitive.ObjectID) (*db.Doctor, error)
+}
+
+type doctorRepository struct {
+	GenericRepository[*db.Doctor]
+	collection *mgm.Collection
+}
+
+// NewDoctorRepository returns a DoctorRepository backed by the doctors collection.
+// It must be called after the MongoDB connection is initialized.
+func NewDoctorRepository() DoctorRepository {
+	return &doctorRepository{
+		GenericRepository: BaseRepository(&db.Doctor{}),
+		collection:        mgm.Coll(&db.Doctor{}),
+	}
+}
+
+func (r *doctorRepository) FindByLicense(license string) (*db.Doctor, error) {
+	doctor := &db.Doctor{}
+	err := r.collection.First(bson.M{"license": license}, doctor)
+	return doctor, err
+}
+
+func (r *doctorRepository) FindByUser(userId primitive.ObjectID) (*db.Doctor, error) {
+	doctor := &db.Doctor{}
+	err := r.collection.First(bson.M{"user": userId}, doctor)
+	return doctor, err
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"health/controllers"
+	"health/middlewares"
+	"health/middlewares/validators"
+	db "health/models/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AppointmentRoute(router *gin.RouterGroup) {
+	appointments := router.Group("/appointments", middlewares.JwtMiddleware(), middlewares.VerifiedEmailMiddleware())
+	{
+		appointments.POST("", middlewares.PermissionMiddleware(db.PermissionAppointmentsWriteOwn), validators.AppointmentValidator(), controllers.BookAppointment)
+		appointments.GET("", middlewares.PermissionMiddleware(db.PermissionAppointmentsReadOwn), controllers.GetAppointments)
+		appointments.GET("/upcoming", middlewares.PermissionMiddleware(db.PermissionAppointmentsUpcoming), controllers.GetUpcomingAppointments)
+		appointments.GET("/:id", middlewares.PermissionMiddleware(db.PermissionAppointmentsRead, db.PermissionAppointmentsReadOwn), validators.PathIdValidator(), controllers.GetAppointment)
+		appointments.PUT("/:id/reschedule", middlewares.PermissionMiddleware(db.PermissionAppointmentsWrite, db.PermissionAppointmentsWriteOwn), validators.PathIdValidator(), validators.RescheduleAppointmentValidator(), controllers.RescheduleAppointment)
+		appointments.POST("/:id/cancel", middlewares.PermissionMiddleware(db.PermissionAppointmentsWrite, db.PermissionAppointmentsWriteOwn), validators.PathIdValidator(), validators.CancelAppointmentValidator(), controllers.CancelAppointment)
+	}
+}
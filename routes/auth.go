@@ -4,6 +4,7 @@ import (
 	"health/controllers"
 	"health/middlewares"
 	"health/middlewares/validators"
+	db "health/models/db"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,7 +14,30 @@ func AuthRoute(router *gin.RouterGroup) {
 	{
 		auth.POST("/register", validators.RegisterValidator(), controllers.Register)
 		auth.POST("/login", validators.LoginValidator(), controllers.Login)
+		auth.GET("/oidc/login", controllers.OIDCLogin)
+		auth.GET("/oidc/callback", validators.OIDCCallbackValidator(), controllers.OIDCCallback)
 		auth.POST("/refresh", validators.RefreshValidator(), controllers.Refresh)
-		auth.GET("/profile", middlewares.JwtMiddleware(), middlewares.RoleMiddleware("admin", "user"), controllers.GetAuthProfile)
+		auth.GET("/verify-email", validators.VerifyEmailValidator(), controllers.VerifyEmail)
+		auth.POST("/verify-email/resend", validators.ResendEmailVerificationValidator(), controllers.ResendEmailVerification)
+		auth.POST("/accept-invitation", validators.AcceptInvitationValidator(), controllers.AcceptInvitation)
+		auth.POST("/magic-link", validators.MagicLinkValidator(), controllers.RequestMagicLink)
+		auth.GET("/magic-link/verify", validators.VerifyMagicLinkValidator(), controllers.VerifyMagicLink)
+		auth.GET("/magic-link/policy", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionSecurityManage), controllers.GetMagicLinkPolicy)
+		auth.PUT("/magic-link/policy", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionSecurityManage), validators.MagicLinkPolicyValidator(), controllers.UpdateMagicLinkPolicy)
+		auth.POST("/forgot-password", validators.ForgotPasswordValidator(), controllers.ForgotPassword)
+		auth.POST("/reset-password", validators.ResetPasswordValidator(), controllers.ResetPassword)
+		auth.POST("/logout", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersWriteSelf), controllers.Logout)
+		auth.POST("/logout-all", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersWriteSelf), controllers.LogoutAll)
+		auth.GET("/sessions", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionSessionsReadOwn), controllers.GetMySessions)
+		auth.DELETE("/sessions/:id", middlewares.JwtMiddleware(), validators.PathIdValidator(), middlewares.PermissionMiddleware(db.PermissionSessionsWriteOwn), controllers.RevokeMySession)
+		auth.POST("/mfa/verify", validators.VerifyMFAValidator(), controllers.VerifyMFA)
+		auth.POST("/mfa/totp/enroll", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersWriteSelf), controllers.EnrollTOTP)
+		auth.POST("/mfa/totp/confirm", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersWriteSelf), validators.ConfirmTOTPValidator(), controllers.ConfirmTOTP)
+		auth.POST("/mfa/totp/disable", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersWriteSelf), validators.DisableMFAValidator(), controllers.DisableTOTP)
+		auth.POST("/mfa/recovery-codes", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersWriteSelf), validators.MFACodeValidator(), controllers.RegenerateRecoveryCodes)
+		auth.GET("/mfa/policy", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionSecurityManage), controllers.GetMFAPolicy)
+		auth.PUT("/mfa/policy", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionSecurityManage), validators.MFAPolicyValidator(), controllers.UpdateMFAPolicy)
+		auth.POST("/change-password", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersWriteSelf), validators.ChangePasswordValidator(), controllers.ChangePassword)
+		auth.GET("/profile", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersReadSelf), controllers.GetAuthProfile)
 	}
 }
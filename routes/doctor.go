@@ -3,6 +3,8 @@ package routes
 import (
 	"health/controllers"
 	"health/middlewares"
+	"health/middlewares/validators"
+	db "health/models/db"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,6 +12,31 @@ import (
 func DoctorRoute(router *gin.RouterGroup) {
 	doctor := router.Group("/doctor")
 	{
-		doctor.GET("/list", middlewares.JwtMiddleware(), controllers.GetDoctors)
+		doctor.GET("/list", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionDoctorsRead), validators.DoctorSearchValidator(), controllers.GetDoctors)
+
+		me := doctor.Group("/me", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionDoctorsWriteSelf), middlewares.DoctorProfileMiddleware())
+		{
+			me.GET("", controllers.GetMyDoctorProfile)
+			me.PUT("", validators.DoctorProfileValidator(), controllers.UpdateMyDoctorProfile)
+			me.PUT("/schedule", validators.ScheduleValidator(), controllers.UpdateMyDoctorSchedule)
+			me.GET("/exceptions", controllers.GetDoctorExceptions)
+			me.POST("/exceptions", validators.ScheduleExceptionValidator(), controllers.CreateDoctorException)
+			me.DELETE("/exceptions/:exceptionId", controllers.DeleteDoctorException)
+			me.GET("/appointments", controllers.GetUpcomingAppointments)
+			me.GET("/verification", controllers.GetDoctorVerification)
+			me.POST("/verification", validators.VerificationSubmissionValidator(), controllers.SubmitDoctorVerification)
+		}
+
+		doctor.POST("", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionDoctorsManage), validators.DoctorValidator(), controllers.CreateDoctor)
+		doctor.GET("/:id", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionDoctorsManage), validators.PathIdValidator(), controllers.GetDoctor)
+		doctor.PUT("/:id", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionDoctorsManage), validators.PathIdValidator(), validators.DoctorValidator(), controllers.UpdateDoctor)
+		doctor.DELETE("/:id", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionDoctorsManage), validators.PathIdValidator(), controllers.DeleteDoctor)
+		doctor.GET("/:id/slots", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionDoctorsRead), validators.PathIdValidator(), controllers.GetDoctorSlots)
+		doctor.GET("/:id/verification", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionDoctorsManage), validators.PathIdValidator(), controllers.GetDoctorVerification)
+		doctor.POST("/:id/verification", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionDoctorsManage), validators.PathIdValidator(), validators.VerificationSubmissionValidator(), controllers.SubmitDoctorVerification)
+		doctor.PUT("/:id/verification", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionDoctorsManage), validators.PathIdValidator(), validators.VerificationTransitionValidator(), controllers.TransitionDoctorVerification)
+		doctor.GET("/:id/exceptions", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionDoctorsManage), validators.PathIdValidator(), controllers.GetDoctorExceptions)
+		doctor.POST("/:id/exceptions", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionDoctorsManage), validators.PathIdValidator(), validators.ScheduleExceptionValidator(), controllers.CreateDoctorException)
+		doctor.DELETE("/:id/exceptions/:exceptionId", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionDoctorsManage), validators.PathIdValidator(), controllers.DeleteDoctorException)
 	}
 }
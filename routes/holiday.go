@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"health/controllers"
+	"health/middlewares"
+	"health/middlewares/validators"
+	db "health/models/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+func HolidayRoute(router *gin.RouterGroup) {
+	holidays := router.Group("/holidays", middlewares.JwtMiddleware())
+	{
+		holidays.GET("", middlewares.PermissionMiddleware(db.PermissionHolidaysRead), controllers.GetHolidays)
+		holidays.POST("", middlewares.PermissionMiddleware(db.PermissionHolidaysManage), validators.HolidayValidator(), controllers.CreateHoliday)
+		holidays.PUT("/:id", middlewares.PermissionMiddleware(db.PermissionHolidaysManage), validators.PathIdValidator(), validators.HolidayValidator(), controllers.UpdateHoliday)
+		holidays.DELETE("/:id", middlewares.PermissionMiddleware(db.PermissionHolidaysManage), validators.PathIdValidator(), controllers.DeleteHoliday)
+	}
+}
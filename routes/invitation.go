@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"health/controllers"
+	"health/middlewares"
+	"health/middlewares/validators"
+	db "health/models/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InvitationRoute(router *gin.RouterGroup) {
+	invitations := router.Group("/invitations", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersManage))
+	{
+		invitations.POST("", validators.InvitationValidator(), controllers.CreateInvitation)
+		invitations.GET("", controllers.GetPendingInvitations)
+		invitations.POST("/:id/resend", validators.PathIdValidator(), controllers.ResendInvitation)
+		invitations.DELETE("/:id", validators.PathIdValidator(), controllers.RevokeInvitation)
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"health/controllers"
+	"health/middlewares"
+	"health/middlewares/validators"
+	db "health/models/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+func NoteRoute(router *gin.RouterGroup) {
+	notes := router.Group("/notes", middlewares.JwtMiddleware())
+	{
+		notes.POST("", middlewares.PermissionMiddleware(db.PermissionNotesWriteOwn), validators.NoteValidator(), controllers.CreateNote)
+		notes.GET("", middlewares.PermissionMiddleware(db.PermissionNotesReadOwn), controllers.GetNotes)
+		notes.GET("/:id", middlewares.PermissionMiddleware(db.PermissionNotesReadOwn), validators.PathIdValidator(), controllers.GetNote)
+		notes.PUT("/:id", middlewares.PermissionMiddleware(db.PermissionNotesWriteOwn), validators.PathIdValidator(), validators.NoteValidator(), controllers.UpdateNote)
+		notes.DELETE("/:id", middlewares.PermissionMiddleware(db.PermissionNotesWriteOwn), validators.PathIdValidator(), controllers.DeleteNote)
+	}
+}
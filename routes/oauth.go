@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"health/controllers"
+	"health/middlewares"
+	"health/middlewares/validators"
+	db "health/models/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+func OAuthRoute(router *gin.RouterGroup) {
+	oauth := router.Group("/oauth")
+	{
+		oauth.POST("/clients", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionOAuthClientsManage), validators.OAuthClientValidator(), controllers.CreateOAuthClient)
+		oauth.GET("/clients", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionOAuthClientsManage), controllers.GetOAuthClients)
+		oauth.DELETE("/clients/:id", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionOAuthClientsManage), validators.PathIdValidator(), controllers.RevokeOAuthClient)
+		oauth.GET("/authorize", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersWriteSelf), validators.OAuthAuthorizeQueryValidator(), controllers.GetOAuthAuthorization)
+		oauth.POST("/authorize", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersWriteSelf), validators.OAuthAuthorizeValidator(), controllers.DecideOAuthAuthorization)
+		// clients authenticate themselves on these endpoints, which answer in the format of RFC 6749
+		oauth.POST("/token", controllers.OAuthToken)
+		oauth.POST("/introspect", controllers.IntrospectOAuthToken)
+		oauth.POST("/revoke", controllers.RevokeOAuthToken)
+	}
+}
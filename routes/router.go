@@ -20,12 +20,18 @@ func New() *gin.Engine {
 	r.Use(gin.LoggerWithWriter(middlewares.LogWriter()))
 	r.Use(gin.CustomRecovery(middlewares.AppRecovery()))
 	r.Use(middlewares.CORSMiddleware())
+	WellKnownRoute(&r.RouterGroup)
 	v1 := r.Group("/v1")
 	{
 		PingRoute(v1)
 		AuthRoute(v1)
 		UserRoute(v1)
 		DoctorRoute(v1)
+		NoteRoute(v1)
+		AppointmentRoute(v1)
+		HolidayRoute(v1)
+		OAuthRoute(v1)
+		InvitationRoute(v1)
 	}
 	docs.SwaggerInfo.BasePath = "/"
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -3,6 +3,8 @@ package routes
 import (
 	"health/controllers"
 	"health/middlewares"
+	"health/middlewares/validators"
+	db "health/models/db"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,9 +12,20 @@ import (
 func UserRoute(router *gin.RouterGroup) {
 	user := router.Group("/user")
 	{
-		user.GET("/list", middlewares.JwtMiddleware(), controllers.GetUsers)
-		user.GET("/:id", middlewares.JwtMiddleware(), controllers.GetUser)
-		user.PUT("/:id", middlewares.JwtMiddleware(), controllers.Update)
-		user.DELETE("/:id", middlewares.JwtMiddleware(), controllers.Delete)
+		user.GET("/list", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersRead), controllers.GetUsers)
+		user.GET("/:id", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersRead, db.PermissionUsersReadSelf), validators.PathIdValidator(), controllers.GetUser)
+		user.PUT("/:id", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersWrite, db.PermissionUsersWriteSelf), validators.PathIdValidator(), controllers.Update)
+		user.DELETE("/:id", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersWrite, db.PermissionUsersWriteSelf), validators.PathIdValidator(), controllers.Delete)
+		user.GET("/:id/sessions", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersManage), validators.PathIdValidator(), controllers.GetUserSessions)
+		user.DELETE("/:id/sessions/:sessionId", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersManage), validators.PathIdValidator(), controllers.RevokeUserSession)
+		user.POST("/:id/unlock", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersManage), validators.PathIdValidator(), controllers.UnlockUser)
+		user.PUT("/:id/role", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersManage), validators.PathIdValidator(), validators.UserRoleValidator(), controllers.ChangeUserRole)
+		user.POST("/:id/suspend", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersManage), validators.PathIdValidator(), validators.UserActionValidator(), controllers.SuspendUser)
+		user.POST("/:id/reactivate", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersManage), validators.PathIdValidator(), validators.UserActionValidator(), controllers.ReactivateUser)
+		user.POST("/:id/logout", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersManage), validators.PathIdValidator(), validators.UserActionValidator(), controllers.ForceLogoutUser)
+		user.GET("/:id/audit-log", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionUsersManage), validators.PathIdValidator(), controllers.GetUserAuditLog)
+		user.POST("/:id/api-keys", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionAPIKeysManage), validators.PathIdValidator(), validators.APIKeyValidator(), controllers.CreateAPIKey)
+		user.GET("/:id/api-keys", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionAPIKeysManage), validators.PathIdValidator(), controllers.GetUserAPIKeys)
+		user.DELETE("/:id/api-keys/:apiKeyId", middlewares.JwtMiddleware(), middlewares.PermissionMiddleware(db.PermissionAPIKeysManage), validators.PathIdValidator(), controllers.RevokeAPIKey)
 	}
 }
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"health/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WellKnownRoute(router *gin.RouterGroup) {
+	wellKnown := router.Group("/.well-known")
+	{
+		wellKnown.GET("/jwks.json", controllers.GetJWKS)
+	}
+}
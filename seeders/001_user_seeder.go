@@ -18,6 +18,7 @@ func init() {
 			"Admin User",
 			models.RoleAdmin,
 		)
+		admin.EmailVarified = true
 
 		// Check if user already exists
 		var existingUser models.User
@@ -3,6 +3,7 @@ package seeders
 import (
 	models "health/models/db"
 	"health/services"
+	"time"
 
 	"github.com/kamva/mgm/v3"
 	"go.mongodb.org/mongo-driver/bson"
@@ -16,29 +17,39 @@ func init() {
 				"Dr. John Smith",
 				"Cardiology",
 				"+1234567890",
-				"10 years",
+				10,
 				"New York",
 				"LIC001",
-				"9 AM - 5 PM",
 				true,
-				[]string{"Monday", "Wednesday", "Friday"},
-				[]string{"09:00", "09:00", "09:00"},
-				[]string{"17:00", "17:00", "17:00"},
+				models.WeeklySchedule{
+					Timezone: "America/New_York",
+					Days: []models.DaySchedule{
+						{Day: "monday", Intervals: []models.WorkInterval{{Start: "09:00", End: "17:00"}}},
+						{Day: "wednesday", Intervals: []models.WorkInterval{{Start: "09:00", End: "17:00"}}},
+						{Day: "friday", Intervals: []models.WorkInterval{{Start: "09:00", End: "17:00"}}},
+					},
+				},
 			),
 			models.NewDoctor(
 				"Dr. Jane Doe",
 				"Pediatrics",
 				"+1234567891",
-				"8 years",
+				8,
 				"Los Angeles",
 				"LIC002",
-				"10 AM - 6 PM",
 				true,
-				[]string{"Tuesday", "Thursday"},
-				[]string{"10:00", "10:00"},
-				[]string{"18:00", "18:00"},
+				models.WeeklySchedule{
+					Timezone: "America/Los_Angeles",
+					Days: []models.DaySchedule{
+						{Day: "tuesday", Intervals: []models.WorkInterval{{Start: "10:00", End: "18:00"}}},
+						{Day: "thursday", Intervals: []models.WorkInterval{{Start: "10:00", End: "18:00"}}},
+					},
+				},
 			),
 		}
+		// Sample coordinates so the doctors show up in a nearby search
+		doctors[0].Coordinates = models.NewGeoPoint(40.7128, -74.0060)
+		doctors[1].Coordinates = models.NewGeoPoint(34.0522, -118.2437)
 
 		for _, doctor := range doctors {
 			// Check if doctor already exists
@@ -49,6 +60,17 @@ func init() {
 				continue
 			}
 
+			// Sample doctors skip onboarding so they can be listed and booked right away
+			doctor.Verification = models.Verification{
+				Status: models.VerificationApproved,
+				History: []models.VerificationEvent{{
+					From:   models.VerificationPending,
+					To:     models.VerificationApproved,
+					Reason: "seeded",
+					At:     time.Now().UTC(),
+				}},
+			}
+
 			// Create doctor
 			if err := mgm.Coll(doctor).Create(doctor); err != nil {
 				return err
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	db "health/models/db"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// APIKeyHeader is the request header API keys are sent in, apart from the Authorization header of users.
+	APIKeyHeader = "X-API-Key"
+	// apiKeyPrefix starts every API key, so that leaked keys are easy to recognize.
+	apiKeyPrefix = "hk_"
+	// apiKeyTouchInterval is how often the last use of an API key is recorded.
+	apiKeyTouchInterval = time.Minute
+)
+
+// CreateAPIKey generates a new API key for the user on behalf of the admin with the given ObjectID.
+// The key is limited to the given scopes, which must all be granted by the role of the user.
+// The returned key is not stored and cannot be retrieved again.
+// If a scope is not granted or the key cannot be saved, an error is returned.
+func CreateAPIKey(actorId primitive.ObjectID, user *db.User, name string, scopes []string, expiresAt *time.Time) (*db.APIKey, string, error) {
+	for _, scope := range scopes {
+		if !db.HasPermission(user.Role, scope) {
+			return nil, "", fmt.Errorf("scope %s is not granted to the %s role", scope, user.Role)
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", errors.New("cannot generate api key")
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	apiKey := db.NewAPIKey(user.ID, name, key[:len(apiKeyPrefix)+8], hashAPIKey(key), scopes, actorId, expiresAt)
+	if err := mgm.Coll(apiKey).Create(apiKey); err != nil {
+		return nil, "", errors.New("cannot create api key")
+	}
+
+	return apiKey, key, nil
+}
+
+// hashAPIKey hashes an API key for storage and lookup. API keys are random, so a fast hash is enough.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAPIKey returns the active API key matching the given key, and records its use.
+// If the key is unknown, revoked or expired, an error is returned.
+func VerifyAPIKey(key string) (*db.APIKey, error) {
+	apiKey := &db.APIKey{}
+	if err := mgm.Coll(apiKey).First(bson.M{"hash": hashAPIKey(key)}, apiKey); err != nil {
+		return nil, errors.New("invalid api key")
+	}
+	if !apiKey.IsActive() {
+		return nil, errors.New("api key is revoked or expired")
+	}
+
+	now := time.Now().UTC()
+	_, _ = mgm.Coll(apiKey).UpdateOne(mgm.Ctx(), bson.M{
+		field.ID: apiKey.ID,
+		"$or": bson.A{
+			bson.M{"last_used_at": bson.M{"$exists": false}},
+			bson.M{"last_used_at": bson.M{"$lt": now.Add(-apiKeyTouchInterval)}},
+		},
+	}, bson.M{"$set": bson.M{"last_used_at": now}})
+
+	return apiKey, nil
+}
+
+// GetUserAPIKeys retrieves the API keys of the user with the given ObjectID, newest first,
+// including revoked and expired ones.
+// If the keys cannot be retrieved, an error is returned.
+func GetUserAPIKeys(userId primitive.ObjectID) ([]db.APIKey, error) {
+	apiKeys := []db.APIKey{}
+	err := mgm.Coll(&db.APIKey{}).SimpleFind(&apiKeys, bson.M{"user": userId},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, errors.New("cannot find api keys")
+	}
+
+	return apiKeys, nil
+}
+
+// RevokeAPIKey revokes the API key with the given ObjectID, as long as it belongs to the given user.
+// If no such active key exists, an error is returned.
+func RevokeAPIKey(userId primitive.ObjectID, apiKeyId primitive.ObjectID) error {
+	now := time.Now().UTC()
+	result, err := mgm.Coll(&db.APIKey{}).UpdateOne(mgm.Ctx(), bson.M{
+		field.ID:     apiKeyId,
+		"user":       userId,
+		"revoked_at": bson.M{"$exists": false},
+	}, bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}})
+	if err != nil || result.MatchedCount == 0 {
+		return errors.New("cannot find api key")
+	}
+
+	return nil
+}
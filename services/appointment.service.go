@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"errors"
+	db "health/models/db"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrSlotTaken is returned when an appointment overlaps an appointment that is already booked.
+var ErrSlotTaken = errors.New("the requested time is already booked")
+
+// appointmentSlots validates that the interval can be booked with the doctor and returns
+// the booking grid cells it covers.
+// The interval must lie in the future, be aligned to the doctor's booking grid and fit
+// completely inside the doctor's available time, which accounts for time off and holidays.
+func appointmentSlots(doctor *db.Doctor, interval Interval) ([]time.Time, error) {
+	if !doctor.Availability || !doctor.IsApproved() {
+		return nil, errors.New("doctor is not accepting appointments")
+	}
+	if !interval.Start.After(time.Now()) {
+		return nil, errors.New("appointment must start in the future")
+	}
+
+	if !onSlotGrid(doctor, interval.Start) || !onSlotGrid(doctor, interval.End) {
+		return nil, errors.New("appointment must start and end on a booking slot boundary")
+	}
+
+	withinSchedule, err := IsWithinSchedule(doctor, interval)
+	if err != nil {
+		return nil, err
+	}
+	if !withinSchedule {
+		return nil, errors.New("appointment is outside the doctor's working hours")
+	}
+
+	slotLength := time.Duration(Config.AppointmentSlotMinutes) * time.Minute
+	var slots []time.Time
+	for slot := interval.Start; slot.Before(interval.End); slot = slot.Add(slotLength) {
+		slots = append(slots, slot)
+	}
+	return slots, nil
+}
+
+// BookAppointment books an appointment of the patient with the doctor between startsAt and endsAt.
+// The booking is checked against the doctor's schedule. If any part of the requested time is
+// already booked, ErrSlotTaken is returned; the check is enforced by a unique index, so it
+// also holds when two requests race for the same slot.
+func BookAppointment(patientId primitive.ObjectID, doctorId primitive.ObjectID, startsAt time.Time, endsAt time.Time, reason string) (*db.Appointment, error) {
+	doctor, err := FindDoctorById(doctorId)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := Interval{Start: startsAt.UTC(), End: endsAt.UTC()}
+	slots, err := appointmentSlots(doctor, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	appointment := db.NewAppointment(doctor.ID, patientId, interval.Start, interval.End, slots, reason)
+	err = mgm.Coll(appointment).Create(appointment)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrSlotTaken
+	}
+	if err != nil {
+		return nil, errors.New("cannot create appointment")
+	}
+
+	return appointment, nil
+}
+
+// FindAppointmentById retrieves an appointment from the MongoDB database by the given ObjectID.
+// If the appointment does not exist, an error is returned.
+func FindAppointmentById(appointmentId primitive.ObjectID) (*db.Appointment, error) {
+	appointment := &db.Appointment{}
+	err := mgm.Coll(appointment).FindByID(appointmentId, appointment)
+	if err != nil {
+		return nil, errors.New("cannot find appointment")
+	}
+
+	return appointment, nil
+}
+
+// RescheduleAppointment moves a booked appointment to the time between startsAt and endsAt.
+// The new time is checked the same way as a new booking, and ErrSlotTaken is returned when
+// it overlaps another booked appointment of the doctor.
+func RescheduleAppointment(appointment *db.Appointment, startsAt time.Time, endsAt time.Time) error {
+	if appointment.Status != db.AppointmentStatusBooked {
+		return errors.New("only booked appointments can be rescheduled")
+	}
+
+	doctor, err := FindDoctorById(appointment.Doctor)
+	if err != nil {
+		return err
+	}
+
+	interval := Interval{Start: startsAt.UTC(), End: endsAt.UTC()}
+	slots, err := appointmentSlots(doctor, interval)
+	if err != nil {
+		return err
+	}
+
+	// only touch the appointment if it is still booked, so a concurrent cancellation wins
+	result, err := mgm.Coll(appointment).UpdateOne(mgm.Ctx(),
+		bson.M{field.ID: appointment.ID, "status": db.AppointmentStatusBooked},
+		bson.M{"$set": bson.M{
+			"starts_at":  interval.Start,
+			"ends_at":    interval.End,
+			"slots":      slots,
+			"updated_at": time.Now().UTC(),
+		}},
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrSlotTaken
+	}
+	if err != nil {
+		return errors.New("cannot reschedule appointment")
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("only booked appointments can be rescheduled")
+	}
+
+	appointment.StartsAt = interval.Start
+	appointment.EndsAt = interval.End
+	appointment.Slots = slots
+	return nil
+}
+
+// CancelAppointment cancels a booked appointment on behalf of the given user, which frees
+// its slots for new bookings.
+func CancelAppointment(appointment *db.Appointment, cancelledBy primitive.ObjectID, reason string) error {
+	if appointment.Status != db.AppointmentStatusBooked {
+		return errors.New("only booked appointments can be cancelled")
+	}
+
+	result, err := mgm.Coll(appointment).UpdateOne(mgm.Ctx(),
+		bson.M{field.ID: appointment.ID, "status": db.AppointmentStatusBooked},
+		bson.M{"$set": bson.M{
+			"status":        db.AppointmentStatusCancelled,
+			"cancel_reason": reason,
+			"cancelled_by":  cancelledBy,
+			"updated_at":    time.Now().UTC(),
+		}},
+	)
+	if err != nil {
+		return errors.New("cannot cancel appointment")
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("only booked appointments can be cancelled")
+	}
+
+	appointment.Status = db.AppointmentStatusCancelled
+	appointment.CancelReason = reason
+	appointment.CancelledBy = cancelledBy
+	return nil
+}
+
+// GetPatientAppointments retrieves the appointments of the patient, most recent first,
+// paginated to the given page and limit, along with their total count.
+func GetPatientAppointments(ctx context.Context, patientId primitive.ObjectID, page int, limit int) ([]db.Appointment, int64, error) {
+	filter := bson.M{"patient": patientId}
+	return findAppointments(ctx, filter, bson.M{"starts_at": -1}, page, limit)
+}
+
+// GetUpcomingAppointments retrieves the booked appointments that have not ended yet, soonest first,
+// paginated to the given page and limit, along with their total count.
+// If doctorId is not the zero ObjectID, only the appointments with that doctor are returned.
+func GetUpcomingAppointments(ctx context.Context, doctorId primitive.ObjectID, page int, limit int) ([]db.Appointment, int64, error) {
+	filter := bson.M{
+		"status":  db.AppointmentStatusBooked,
+		"ends_at": bson.M{"$gt": time.Now()},
+	}
+	if !doctorId.IsZero() {
+		filter["doctor"] = doctorId
+	}
+	return findAppointments(ctx, filter, bson.M{"starts_at": 1}, page, limit)
+}
+
+// findAppointments retrieves a page of the appointments matching the filter in the given order,
+// along with the total number of matching appointments.
+func findAppointments(ctx context.Context, filter bson.M, sort bson.M, page int, limit int) ([]db.Appointment, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	skip := (page - 1) * limit
+	appointments := []db.Appointment{}
+	opts := options.Find()
+	opts.SetLimit(int64(limit))
+	opts.SetSkip(int64(skip))
+	opts.SetSort(sort)
+	err := mgm.Coll(&db.Appointment{}).SimpleFind(&appointments, filter, opts)
+
+	if err != nil {
+		return nil, 0, errors.New("cannot find appointments")
+	}
+	total, _ := mgm.Coll(&db.Appointment{}).CountDocuments(ctx, filter)
+	return appointments, total, nil
+}
+
+// bookedAppointmentsBetween retrieves the booked appointments of the doctor that overlap the interval.
+func bookedAppointmentsBetween(doctorId primitive.ObjectID, interval Interval) ([]db.Appointment, error) {
+	appointments := []db.Appointment{}
+	err := mgm.Coll(&db.Appointment{}).SimpleFind(&appointments, bson.M{
+		"doctor":    doctorId,
+		"status":    db.AppointmentStatusBooked,
+		"starts_at": bson.M{"$lt": interval.End},
+		"ends_at":   bson.M{"$gt": interval.Start},
+	})
+	if err != nil {
+		return nil, errors.New("cannot find appointments")
+	}
+	return appointments, nil
+}
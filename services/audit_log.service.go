@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"errors"
+	db "health/models/db"
+	"health/utils"
+	"log"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// recordAuditLog stores the audit log entry. Unlike security events, admin actions must not go
+// unrecorded, so the entry is stored before the action is applied, and if it cannot be stored, an error is returned.
+func recordAuditLog(entry *db.AuditLog) error {
+	log.Printf("Admin %s: %s on user %s: %s", entry.Actor.Hex(), entry.Action, entry.User.Hex(), entry.Reason)
+	if err := mgm.Coll(entry).Create(entry); err != nil {
+		return errors.New("cannot record audit log")
+	}
+
+	return nil
+}
+
+// discardAuditLog removes the audit log entry of an action that could not be applied after all.
+func discardAuditLog(entry *db.AuditLog) {
+	if err := mgm.Coll(entry).Delete(entry); err != nil {
+		log.Printf("Cannot discard audit log %s of an action that was not applied: %v", entry.ID.Hex(), err)
+	}
+}
+
+// GetUserAuditLog retrieves the admin actions taken on the account of the user with the given
+// ObjectID, most recent first, paginated to the given page and limit, along with their total count.
+// If the entries cannot be retrieved, an error is returned.
+func GetUserAuditLog(ctx context.Context, userId primitive.ObjectID, page int, limit int) ([]db.AuditLog, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = utils.DefaultPerPage
+	}
+	filter := bson.M{"user": userId}
+	entries := []db.AuditLog{}
+	opts := options.Find()
+	opts.SetLimit(int64(limit))
+	opts.SetSkip(int64((page - 1) * limit))
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if err := mgm.Coll(&db.AuditLog{}).SimpleFind(&entries, filter, opts); err != nil {
+		return nil, 0, errors.New("cannot find audit log")
+	}
+	total, err := mgm.Coll(&db.AuditLog{}).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, errors.New("cannot count audit log")
+	}
+
+	return entries, total, nil
+}
@@ -13,6 +13,28 @@ func LoadConfig() {
 	v.AutomaticEnv()
 	v.SetDefault("SERVER_PORT", "8080")
 	v.SetDefault("MODE", "debug")
+	v.SetDefault("APPOINTMENT_SLOT_MINUTES", 15)
+	v.SetDefault("APP_URL", "http://localhost:8080")
+	v.SetDefault("MAIL_TRANSPORT", "log")
+	v.SetDefault("MAIL_FROM", "no-reply@localhost.localdomain")
+	v.SetDefault("MAIL_FILE_DIR", "storage/mail")
+	v.SetDefault("EMAIL_VERIFICATION_REQUIRED", "none")
+	v.SetDefault("EMAIL_VERIFICATION_EXPIRATION_HOURS", 24)
+	v.SetDefault("PASSWORD_RESET_EXPIRATION_MINUTES", 30)
+	v.SetDefault("PASSWORD_RESET_MAX_REQUESTS", 3)
+	v.SetDefault("PASSWORD_RESET_MAX_REQUESTS_PER_IP", 10)
+	v.SetDefault("MFA_ISSUER", "Health API")
+	v.SetDefault("LOGIN_MAX_ATTEMPTS", 5)
+	v.SetDefault("LOGIN_MAX_ATTEMPTS_PER_IP", 20)
+	v.SetDefault("LOGIN_ATTEMPT_WINDOW_MINUTES", 60)
+	v.SetDefault("LOGIN_LOCKOUT_MINUTES", 1)
+	v.SetDefault("LOGIN_MAX_LOCKOUT_MINUTES", 60)
+	v.SetDefault("INVITATION_EXPIRATION_HOURS", 72)
+	v.SetDefault("MAGIC_LINK_EXPIRATION_MINUTES", 15)
+	v.SetDefault("MAGIC_LINK_MAX_REQUESTS", 3)
+	v.SetDefault("MAGIC_LINK_MAX_REQUESTS_PER_IP", 10)
+	v.SetDefault("OIDC_SCOPES", "openid email profile")
+	v.SetDefault("OIDC_DEFAULT_ROLE", "user")
 	v.SetConfigType("dotenv")
 	v.SetConfigName(".env")
 	v.AddConfigPath("./")
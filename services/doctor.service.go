@@ -2,36 +2,679 @@ package services
 
 import (
 	"context"
+	"encoding/csv"
 	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"health/models"
 	db "health/models/db"
+	"health/repositories"
+	"health/utils"
 
 	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func GetDoctors(ctx context.Context, page int, limit int, nameFilter string) ([]db.Doctor, int64, error) {
+// GetDoctors retrieves the approved doctors matching the search, paginated to the given page and limit,
+// along with the total number of matching doctors.
+// All filters are backed by indexes: name and location match normalized prefixes, and the
+// availability filter accounts for time off and clinic holidays in the query itself so that
+// the total stays accurate.
+func GetDoctors(ctx context.Context, page int, limit int, search *models.DoctorSearchRequest) ([]db.Doctor, int64, error) {
 	if page < 1 {
 		page = 1
 	}
 	if limit < 1 {
-		limit = 10
+		limit = utils.DefaultPerPage
 	}
 	skip := (page - 1) * limit
-	filter := bson.M{}
-	if nameFilter != "" {
-		filter["name"] = bson.M{"$regex": nameFilter, "$options": "i"} // Case-insensitive search
+
+	now := time.Now()
+	onLeaveIds, holidayTimezones, err := doctorsOnLeave(now)
+	if err != nil {
+		return nil, 0, err
 	}
-	var doctors []db.Doctor
+
+	filter := doctorSearchFilter(search, onLeaveIds, holidayTimezones)
+
+	order := 1
+	if search.Order == "desc" {
+		order = -1
+	}
+	sortField := "search_name"
+	if search.Sort == "experience" {
+		sortField = "experience_years"
+	}
+
+	doctors := []db.Doctor{}
 	opts := options.Find()
 	opts.SetLimit(int64(limit))
 	opts.SetSkip(int64(skip))
-	err := mgm.Coll(&db.Doctor{}).SimpleFind(&doctors, filter, opts)
+	opts.SetSort(bson.D{{Key: sortField, Value: order}, {Key: field.ID, Value: 1}})
+	err = mgm.Coll(&db.Doctor{}).SimpleFind(&doctors, filter, opts)
 
 	if err != nil {
 		return nil, 0, errors.New("cannot find doctors")
 	}
+	found := make([]*db.Doctor, len(doctors))
+	for i := range doctors {
+		found[i] = &doctors[i]
+	}
+	markDoctorsOnLeave(found, onLeaveIds, holidayTimezones)
 	total, _ := mgm.Coll(&db.Doctor{}).CountDocuments(ctx, filter)
 	return doctors, total, nil
 }
+
+// GetNearbyDoctors retrieves the approved doctors matching the search that have coordinates within
+// the search radius of the searched point, nearest first and paginated to the given page and limit,
+// along with their distance in kilometers and the total number of matching doctors.
+// The sort and order of the search are ignored. The radius defaults to 10 kilometers.
+func GetNearbyDoctors(ctx context.Context, page int, limit int, search *models.DoctorSearchRequest) ([]db.NearbyDoctor, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = utils.DefaultPerPage
+	}
+	skip := (page - 1) * limit
+	radius := search.Radius
+	if radius <= 0 {
+		radius = 10
+	}
+
+	onLeaveIds, holidayTimezones, err := doctorsOnLeave(time.Now())
+	if err != nil {
+		return nil, 0, err
+	}
+	filter := doctorSearchFilter(search, onLeaveIds, holidayTimezones)
+	point := db.NewGeoPoint(*search.Lat, *search.Lng)
+
+	// $geoNear has to be the first stage, it filters, sorts by distance and sets the distance in one go
+	pipeline := mongo.Pipeline{
+		{{Key: "$geoNear", Value: bson.D{
+			{Key: "near", Value: point},
+			{Key: "distanceField", Value: "distance"},
+			{Key: "distanceMultiplier", Value: 0.001},
+			{Key: "maxDistance", Value: radius * 1000},
+			{Key: "query", Value: filter},
+			{Key: "spherical", Value: true},
+		}}},
+		{{Key: "$skip", Value: skip}},
+		{{Key: "$limit", Value: limit}},
+	}
+	cursor, err := mgm.Coll(&db.Doctor{}).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, errors.New("cannot find doctors")
+	}
+	doctors := []db.NearbyDoctor{}
+	if err := cursor.All(ctx, &doctors); err != nil {
+		return nil, 0, errors.New("cannot find doctors")
+	}
+	found := make([]*db.Doctor, len(doctors))
+	for i := range doctors {
+		found[i] = &doctors[i].Doctor
+	}
+	markDoctorsOnLeave(found, onLeaveIds, holidayTimezones)
+
+	// $near cannot be counted, $centerSphere covers the same area and takes the radius in radians
+	filter["coordinates"] = bson.M{"$geoWithin": bson.M{
+		"$centerSphere": bson.A{point.Coordinates, radius / earthRadiusKm},
+	}}
+	total, _ := mgm.Coll(&db.Doctor{}).CountDocuments(ctx, filter)
+	return doctors, total, nil
+}
+
+// earthRadiusKm is the equatorial radius of the earth in kilometers, as used by MongoDB.
+const earthRadiusKm = 6378.1
+
+// doctorSearchFilter returns the filter on the approved doctors matching the search, where the
+// available filter takes the doctors on time off and the timezones on a clinic holiday into account.
+func doctorSearchFilter(search *models.DoctorSearchRequest, onLeaveIds []primitive.ObjectID, holidayTimezones []string) bson.M {
+	// only doctors with a verified license are listed
+	filter := bson.M{"verification.status": db.VerificationApproved}
+	if name := db.NormalizeSearch(search.Name); name != "" {
+		filter["search_name"] = bson.M{"$regex": "^" + regexp.QuoteMeta(name)}
+	}
+	if location := db.NormalizeSearch(search.Location); location != "" {
+		filter["search_location"] = bson.M{"$regex": "^" + regexp.QuoteMeta(location)}
+	}
+	if search.Specialization != "" {
+		filter["specialization"] = search.Specialization
+	}
+	if search.Day != "" {
+		filter["schedule.days.day"] = search.Day
+	}
+	if search.MinExperience > 0 {
+		filter["experience_years"] = bson.M{"$gte": search.MinExperience}
+	}
+	switch search.Available {
+	case "true":
+		filter["availability"] = true
+		filter["_id"] = bson.M{"$nin": onLeaveIds}
+		filter["schedule.timezone"] = bson.M{"$nin": holidayTimezones}
+	case "false":
+		filter["$or"] = bson.A{
+			bson.M{"availability": false},
+			bson.M{"_id": bson.M{"$in": onLeaveIds}},
+			bson.M{"schedule.timezone": bson.M{"$in": holidayTimezones}},
+		}
+	}
+
+	return filter
+}
+
+// doctorsOnLeave returns the ids of the doctors that are on time off at the given time, and the
+// doctor timezones in which the given time falls on a clinic holiday.
+func doctorsOnLeave(at time.Time) ([]primitive.ObjectID, []string, error) {
+	var timeOff []db.ScheduleException
+	err := mgm.Coll(&db.ScheduleException{}).SimpleFind(&timeOff, bson.M{
+		"type":      db.ScheduleExceptionTimeOff,
+		"starts_at": bson.M{"$lte": at},
+		"ends_at":   bson.M{"$gt": at},
+	})
+	if err != nil {
+		return nil, nil, errors.New("cannot find schedule exceptions")
+	}
+	ids := []primitive.ObjectID{}
+	for _, exception := range timeOff {
+		ids = append(ids, exception.Doctor)
+	}
+
+	// local dates differ from the UTC date by at most a day in either direction
+	var holidays []db.Holiday
+	err = mgm.Coll(&db.Holiday{}).SimpleFind(&holidays, bson.M{"date": bson.M{"$in": []string{
+		at.UTC().AddDate(0, 0, -1).Format(db.HolidayDateLayout),
+		at.UTC().Format(db.HolidayDateLayout),
+		at.UTC().AddDate(0, 0, 1).Format(db.HolidayDateLayout),
+	}}})
+	if err != nil {
+		return nil, nil, errors.New("cannot find holidays")
+	}
+	timezones := []string{}
+	if len(holidays) == 0 {
+		return ids, timezones, nil
+	}
+	holidayDates := map[string]bool{}
+	for _, holiday := range holidays {
+		holidayDates[holiday.Date] = true
+	}
+
+	doctorTimezones, err := mgm.Coll(&db.Doctor{}).Distinct(mgm.Ctx(), "schedule.timezone", bson.M{})
+	if err != nil {
+		return nil, nil, errors.New("cannot find doctor timezones")
+	}
+	for _, value := range doctorTimezones {
+		timezone, _ := value.(string)
+		localDate := at.In(db.WeeklySchedule{Timezone: timezone}.Location()).Format(db.HolidayDateLayout)
+		if holidayDates[localDate] {
+			timezones = append(timezones, timezone)
+		}
+	}
+
+	return ids, timezones, nil
+}
+
+// markDoctorsOnLeave clears the Availability flag of the doctors that are among the doctors
+// on time off or in a timezone on a clinic holiday. The change is not saved.
+func markDoctorsOnLeave(doctors []*db.Doctor, onLeaveIds []primitive.ObjectID, holidayTimezones []string) {
+	onLeave := map[primitive.ObjectID]bool{}
+	for _, id := range onLeaveIds {
+		onLeave[id] = true
+	}
+	onHoliday := map[string]bool{}
+	for _, timezone := range holidayTimezones {
+		onHoliday[timezone] = true
+	}
+
+	for _, doctor := range doctors {
+		if onLeave[doctor.ID] || onHoliday[doctor.Schedule.Timezone] {
+			doctor.Availability = false
+		}
+	}
+}
+
+// FindDoctorById retrieves a doctor from the MongoDB database by the given ObjectID.
+// If the doctor does not exist, an error is returned.
+func FindDoctorById(doctorId primitive.ObjectID) (*db.Doctor, error) {
+	doctor, err := repositories.NewDoctorRepository().FindByID(doctorId.Hex())
+	if err != nil {
+		return nil, errors.New("cannot find doctor")
+	}
+
+	return doctor, nil
+}
+
+// FindDoctorByUser retrieves the doctor profile linked to the user with the given ObjectID.
+// If the user has no doctor profile, an error is returned.
+func FindDoctorByUser(userId primitive.ObjectID) (*db.Doctor, error) {
+	doctor, err := repositories.NewDoctorRepository().FindByUser(userId)
+	if err != nil {
+		return nil, errors.New("cannot find doctor profile")
+	}
+
+	return doctor, nil
+}
+
+// FindDoctorByLicense retrieves the doctor with the given license number.
+// If no doctor has that license, an error is returned.
+func FindDoctorByLicense(license string) (*db.Doctor, error) {
+	doctor, err := repositories.NewDoctorRepository().FindByLicense(license)
+	if err != nil {
+		return nil, errors.New("cannot find doctor")
+	}
+
+	return doctor, nil
+}
+
+// doctorUser resolves the user a doctor profile is linked to from the given hex id.
+// An empty id resolves to the zero ObjectID, which leaves the profile unlinked.
+// The user must have the doctor role and must not be linked to a doctor other than doctorId.
+func doctorUser(userIdHex string, doctorId primitive.ObjectID) (primitive.ObjectID, error) {
+	if userIdHex == "" {
+		return primitive.NilObjectID, nil
+	}
+
+	userId, _ := primitive.ObjectIDFromHex(userIdHex)
+	user, err := FindUserById(userId)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if user.Role != db.RoleDoctor {
+		return primitive.NilObjectID, errors.New("user must have the doctor role")
+	}
+	if linked, err := FindDoctorByUser(userId); err == nil && linked.ID != doctorId {
+		return primitive.NilObjectID, errors.New("user is already linked to another doctor")
+	}
+
+	return userId, nil
+}
+
+// CreateDoctor creates a new doctor from the given request, linked to the requested user if any.
+// The license must not be in use by another doctor.
+// If the doctor cannot be created, an error is returned.
+func CreateDoctor(request *models.DoctorRequest) (*db.Doctor, error) {
+	repository := repositories.NewDoctorRepository()
+	if _, err := FindDoctorByLicense(request.License); err == nil {
+		return nil, errors.New("license is already in use")
+	}
+	userId, err := doctorUser(request.UserId, primitive.NilObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	doctor := db.NewDoctor(
+		strings.TrimSpace(request.Name),
+		request.Specialization,
+		request.Phone,
+		request.ExperienceYears,
+		strings.TrimSpace(request.Location),
+		request.License,
+		request.Availability,
+		request.Schedule,
+	)
+	doctor.Coordinates = request.Coordinates
+	doctor.User = userId
+	err = repository.Create(doctor)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, errors.New("license or user is already in use")
+	}
+	if err != nil {
+		return nil, errors.New("cannot create doctor")
+	}
+
+	return doctor, nil
+}
+
+// UpdateDoctor replaces the profile, schedule and user link of the doctor with the given ObjectID
+// with the given request. The license must not be in use by another doctor.
+// Coordinates left out of the request keep their current value.
+// If the doctor does not exist or cannot be updated, an error is returned.
+func UpdateDoctor(doctorId primitive.ObjectID, request *models.DoctorRequest) (*db.Doctor, error) {
+	repository := repositories.NewDoctorRepository()
+	doctor, err := repository.FindByID(doctorId.Hex())
+	if err != nil {
+		return nil, errors.New("cannot find doctor")
+	}
+	if other, err := FindDoctorByLicense(request.License); err == nil && other.ID != doctor.ID {
+		return nil, errors.New("license is already in use")
+	}
+	userId, err := doctorUser(request.UserId, doctor.ID)
+	if err != nil {
+		return nil, err
+	}
+	unlink := userId.IsZero() && !doctor.User.IsZero()
+
+	doctor.Name = strings.TrimSpace(request.Name)
+	doctor.Specialization = request.Specialization
+	doctor.Phone = request.Phone
+	doctor.ExperienceYears = request.ExperienceYears
+	doctor.Location = strings.TrimSpace(request.Location)
+	if request.Coordinates != nil {
+		doctor.Coordinates = request.Coordinates
+	}
+	doctor.License = request.License
+	doctor.Availability = request.Availability
+	doctor.Schedule = request.Schedule
+	doctor.User = userId
+	err = repository.Update(doctor)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, errors.New("license or user is already in use")
+	}
+	if err != nil {
+		return nil, errors.New("cannot update doctor")
+	}
+	if unlink {
+		// an empty user is left out of the update, so the link has to be removed explicitly
+		_, err = mgm.Coll(doctor).UpdateOne(mgm.Ctx(), bson.M{field.ID: doctor.ID}, bson.M{"$unset": bson.M{"user": ""}})
+		if err != nil {
+			return nil, errors.New("cannot unlink doctor from user")
+		}
+	}
+
+	return doctor, nil
+}
+
+// UpdateDoctorProfile updates the fields of the doctor a doctor may edit on their own profile.
+// Coordinates and availability left out of the request keep their current value.
+// If the doctor cannot be updated, an error is returned.
+func UpdateDoctorProfile(doctor *db.Doctor, request *models.DoctorProfileRequest) error {
+	doctor.Phone = request.Phone
+	doctor.ExperienceYears = request.ExperienceYears
+	doctor.Location = strings.TrimSpace(request.Location)
+	if request.Coordinates != nil {
+		doctor.Coordinates = request.Coordinates
+	}
+	if request.Availability != nil {
+		doctor.Availability = *request.Availability
+	}
+	if err := repositories.NewDoctorRepository().Update(doctor); err != nil {
+		return errors.New("cannot update doctor")
+	}
+
+	return nil
+}
+
+// UpdateDoctorSchedule replaces the weekly schedule of the doctor.
+// Booked appointments are kept even if they fall outside the new schedule.
+// If the doctor cannot be updated, an error is returned.
+func UpdateDoctorSchedule(doctor *db.Doctor, schedule db.WeeklySchedule) error {
+	doctor.Schedule = schedule
+	if err := repositories.NewDoctorRepository().Update(doctor); err != nil {
+		return errors.New("cannot update schedule")
+	}
+
+	return nil
+}
+
+// DeleteDoctor deletes the doctor with the given ObjectID along with its schedule exceptions.
+// A doctor with upcoming booked appointments cannot be deleted; they have to be cancelled first.
+// If the doctor does not exist or the deletion fails, an error is returned.
+func DeleteDoctor(ctx context.Context, doctorId primitive.ObjectID) error {
+	upcoming, err := mgm.Coll(&db.Appointment{}).CountDocuments(ctx, bson.M{
+		"doctor":  doctorId,
+		"status":  db.AppointmentStatusBooked,
+		"ends_at": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return errors.New("cannot delete doctor")
+	}
+	if upcoming > 0 {
+		return fmt.Errorf("doctor has %d upcoming appointment(s), cancel them first", upcoming)
+	}
+
+	if err := repositories.NewDoctorRepository().Delete(doctorId.Hex()); err != nil {
+		return errors.New("cannot delete doctor")
+	}
+	_, _ = mgm.Coll(&db.ScheduleException{}).DeleteMany(ctx, bson.M{"doctor": doctorId})
+
+	return nil
+}
+
+// legacyDoctorSchedule holds the schedule fields doctors were stored with before WeeklySchedule,
+// where the n-th entries of the three slices described one working interval.
+type legacyDoctorSchedule struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	Name        string             `bson:"name"`
+	WorkDays    []string           `bson:"work_days"`
+	WorkTime    []string           `bson:"work_time"`
+	WorkTimeEnd []string           `bson:"work_time_end"`
+}
+
+// LegacyWeeklySchedule converts the parallel work days, start times and end times of the
+// legacy doctor schedule into a WeeklySchedule in the given timezone.
+// Entries of the same day are merged, and times such as "9:00" are zero padded.
+// If the slices do not line up or the resulting schedule is invalid, an error is returned.
+func LegacyWeeklySchedule(workDays []string, workTime []string, workTimeEnd []string, timezone string) (db.WeeklySchedule, error) {
+	schedule := db.WeeklySchedule{Timezone: timezone, Days: []db.DaySchedule{}}
+	if len(workDays) != len(workTime) || len(workDays) != len(workTimeEnd) {
+		return schedule, fmt.Errorf("work_days, work_time and work_time_end have %d, %d and %d entries", len(workDays), len(workTime), len(workTimeEnd))
+	}
+
+	intervals := map[string][]db.WorkInterval{}
+	for i, workDay := range workDays {
+		day, err := db.ParseWeekday(workDay)
+		if err != nil {
+			return schedule, err
+		}
+		start, err := time.Parse(db.ClockLayout, strings.TrimSpace(workTime[i]))
+		if err != nil {
+			return schedule, fmt.Errorf("invalid work time %q", workTime[i])
+		}
+		end, err := time.Parse(db.ClockLayout, strings.TrimSpace(workTimeEnd[i]))
+		if err != nil {
+			return schedule, fmt.Errorf("invalid work time end %q", workTimeEnd[i])
+		}
+		intervals[day] = append(intervals[day], db.WorkInterval{
+			Start: start.Format(db.ClockLayout),
+			End:   end.Format(db.ClockLayout),
+		})
+	}
+
+	for _, day := range db.Weekdays {
+		if len(intervals[day]) == 0 {
+			continue
+		}
+		sort.Slice(intervals[day], func(i, j int) bool {
+			return intervals[day][i].Start < intervals[day][j].Start
+		})
+		schedule.Days = append(schedule.Days, db.DaySchedule{Day: day, Intervals: intervals[day]})
+	}
+
+	return schedule, schedule.Validate()
+}
+
+// ConvertLegacyDoctorSchedules rewrites every doctor that is still stored with the legacy
+// work_days, work_time and work_time_end fields to a WeeklySchedule in the given timezone,
+// and removes the legacy fields along with the free text work_hours.
+// Doctors that cannot be converted are logged and left untouched. When dryRun is set,
+// nothing is written. The numbers of converted and failed doctors are returned.
+func ConvertLegacyDoctorSchedules(timezone string, dryRun bool) (int, int, error) {
+	collection := mgm.Coll(&db.Doctor{})
+	cursor, err := collection.Find(mgm.Ctx(), bson.M{"schedule": bson.M{"$exists": false}})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find doctors: %w", err)
+	}
+
+	var legacyDoctors []legacyDoctorSchedule
+	if err := cursor.All(mgm.Ctx(), &legacyDoctors); err != nil {
+		return 0, 0, fmt.Errorf("failed to read doctors: %w", err)
+	}
+
+	converted, failed := 0, 0
+	for _, legacy := range legacyDoctors {
+		schedule, err := LegacyWeeklySchedule(legacy.WorkDays, legacy.WorkTime, legacy.WorkTimeEnd, timezone)
+		if err != nil {
+			log.Printf("Cannot convert schedule of doctor %s (%s): %v", legacy.ID.Hex(), legacy.Name, err)
+			failed++
+			continue
+		}
+
+		if !dryRun {
+			_, err = collection.UpdateOne(mgm.Ctx(), bson.M{field.ID: legacy.ID}, bson.M{
+				"$set":   bson.M{"schedule": schedule, "updated_at": time.Now().UTC()},
+				"$unset": bson.M{"work_days": "", "work_time": "", "work_time_end": "", "work_hours": ""},
+			})
+			if err != nil {
+				return converted, failed, fmt.Errorf("failed to update doctor %s: %w", legacy.ID.Hex(), err)
+			}
+		}
+
+		log.Printf("Converted schedule of doctor %s (%s): %d working day(s)", legacy.ID.Hex(), legacy.Name, len(schedule.Days))
+		converted++
+	}
+
+	return converted, failed, nil
+}
+
+// legacyExperiencePattern matches the leading number of a free text experience such as "10 years".
+var legacyExperiencePattern = regexp.MustCompile(`^\s*(\d+)`)
+
+// legacyDoctorExperience is a doctor as stored before the experience became a number of years.
+type legacyDoctorExperience struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Name       string             `bson:"name"`
+	Location   string             `bson:"location"`
+	Experience string             `bson:"experience"`
+}
+
+// ConvertLegacyDoctorExperience rewrites every doctor that is still stored with the free text
+// experience field to experience_years, and backfills the normalized search fields used by
+// the doctor search. Doctors whose experience has no leading number are logged and left
+// untouched. When dryRun is set, nothing is written. The numbers of converted and failed
+// doctors are returned.
+func ConvertLegacyDoctorExperience(dryRun bool) (int, int, error) {
+	collection := mgm.Coll(&db.Doctor{})
+	cursor, err := collection.Find(mgm.Ctx(), bson.M{"experience_years": bson.M{"$exists": false}})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find doctors: %w", err)
+	}
+
+	var legacyDoctors []legacyDoctorExperience
+	if err := cursor.All(mgm.Ctx(), &legacyDoctors); err != nil {
+		return 0, 0, fmt.Errorf("failed to read doctors: %w", err)
+	}
+
+	converted, failed := 0, 0
+	for _, legacy := range legacyDoctors {
+		match := legacyExperiencePattern.FindStringSubmatch(legacy.Experience)
+		if match == nil {
+			log.Printf("Cannot convert experience of doctor %s (%s): %q", legacy.ID.Hex(), legacy.Name, legacy.Experience)
+			failed++
+			continue
+		}
+		years, err := strconv.Atoi(match[1])
+		if err != nil {
+			log.Printf("Cannot convert experience of doctor %s (%s): %v", legacy.ID.Hex(), legacy.Name, err)
+			failed++
+			continue
+		}
+
+		if !dryRun {
+			_, err = collection.UpdateOne(mgm.Ctx(), bson.M{field.ID: legacy.ID}, bson.M{
+				"$set": bson.M{
+					"experience_years": years,
+					"search_name":      db.NormalizeSearch(legacy.Name),
+					"search_location":  db.NormalizeSearch(legacy.Location),
+					"updated_at":       time.Now().UTC(),
+				},
+				"$unset": bson.M{"experience": ""},
+			})
+			if err != nil {
+				return converted, failed, fmt.Errorf("failed to update doctor %s: %w", legacy.ID.Hex(), err)
+			}
+		}
+
+		log.Printf("Converted experience of doctor %s (%s): %d year(s)", legacy.ID.Hex(), legacy.Name, years)
+		converted++
+	}
+
+	return converted, failed, nil
+}
+
+// LoadGazetteer reads a CSV gazetteer with a header row and name, latitude and longitude columns,
+// and returns the points it lists by normalized place name.
+func LoadGazetteer(path string) (map[string]*db.GeoPoint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gazetteer: %w", err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gazetteer: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("gazetteer is empty")
+	}
+
+	places := map[string]*db.GeoPoint{}
+	for i, record := range records[1:] {
+		if len(record) < 3 {
+			return nil, fmt.Errorf("gazetteer line %d: expected name, latitude and longitude", i+2)
+		}
+		latitude, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("gazetteer line %d: invalid latitude %q", i+2, record[1])
+		}
+		longitude, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("gazetteer line %d: invalid longitude %q", i+2, record[2])
+		}
+		point := db.NewGeoPoint(latitude, longitude)
+		if err := point.Validate(); err != nil {
+			return nil, fmt.Errorf("gazetteer line %d: %w", i+2, err)
+		}
+		places[db.NormalizeSearch(record[0])] = point
+	}
+
+	return places, nil
+}
+
+// BackfillDoctorCoordinates sets the coordinates of every doctor without coordinates whose location
+// is a place of the gazetteer. Doctors whose location is not in the gazetteer are logged and left
+// untouched. When dryRun is set, nothing is written. The numbers of updated and unmatched doctors
+// are returned.
+func BackfillDoctorCoordinates(gazetteer map[string]*db.GeoPoint, dryRun bool) (int, int, error) {
+	collection := mgm.Coll(&db.Doctor{})
+	var doctors []db.Doctor
+	err := collection.SimpleFind(&doctors, bson.M{"coordinates": bson.M{"$exists": false}})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find doctors: %w", err)
+	}
+
+	updated, unmatched := 0, 0
+	for _, doctor := range doctors {
+		point, ok := gazetteer[db.NormalizeSearch(doctor.Location)]
+		if !ok {
+			log.Printf("No coordinates for doctor %s (%s) at %q", doctor.ID.Hex(), doctor.Name, doctor.Location)
+			unmatched++
+			continue
+		}
+
+		if !dryRun {
+			_, err = collection.UpdateOne(mgm.Ctx(), bson.M{field.ID: doctor.ID}, bson.M{
+				"$set": bson.M{"coordinates": point, "updated_at": time.Now().UTC()},
+			})
+			if err != nil {
+				return updated, unmatched, fmt.Errorf("failed to update doctor %s: %w", doctor.ID.Hex(), err)
+			}
+		}
+
+		log.Printf("Set coordinates of doctor %s (%s) at %q", doctor.ID.Hex(), doctor.Name, doctor.Location)
+		updated++
+	}
+
+	return updated, unmatched, nil
+}
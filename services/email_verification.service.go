@@ -0,0 +1,99 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	db "health/models/db"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// emailVerificationResendInterval is the minimum time between two verification emails to the same user.
+const emailVerificationResendInterval = time.Minute
+
+// SendEmailVerification replaces the pending email verification tokens of the user with a new
+// signed token that expires after EMAIL_VERIFICATION_EXPIRATION_HOURS, and mails the user a link
+// to verify their address with it.
+// If the token cannot be created or the email cannot be sent, an error is returned.
+func SendEmailVerification(user *db.User) error {
+	_, err := mgm.Coll(&db.Token{}).DeleteMany(mgm.Ctx(), bson.M{"user": user.ID, "type": db.TokenTypeEmailVerification})
+	if err != nil {
+		return errors.New("cannot replace email verification token")
+	}
+
+	expiresAt := time.Now().Add(time.Duration(Config.EmailVerificationHours) * time.Hour)
+	token, err := CreateToken(user, db.TokenTypeEmailVerification, expiresAt)
+	if err != nil {
+		return errors.New("cannot create email verification token")
+	}
+
+	link := Config.AppURL + "/v1/auth/verify-email?token=" + url.QueryEscape(token.Token)
+	return SendMail(Mail{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hello %s,\n\nPlease verify your email address by opening the link below:\n\n%s\n\nThe link expires in %d hour(s).\n",
+			user.Name, link, Config.EmailVerificationHours),
+	})
+}
+
+// ResendEmailVerification sends a new verification email to the unverified user with the given
+// email address. Unknown and verified addresses are ignored, so that the response does not reveal
+// which addresses are registered, and at most one email is sent per minute to the same user.
+// If the email cannot be sent, the failure is only logged, for the same reason.
+func ResendEmailVerification(email string) {
+	user, err := FindUserByEmail(email)
+	if err != nil || user.EmailVarified {
+		return
+	}
+
+	recent, err := mgm.Coll(&db.Token{}).CountDocuments(mgm.Ctx(), bson.M{
+		"user":       user.ID,
+		"type":       db.TokenTypeEmailVerification,
+		"created_at": bson.M{"$gt": time.Now().Add(-emailVerificationResendInterval)},
+	})
+	if err != nil || recent > 0 {
+		return
+	}
+
+	if err := SendEmailVerification(user); err != nil {
+		log.Printf("Cannot resend email verification to user %s: %v", user.ID.Hex(), err)
+	}
+}
+
+// VerifyEmail marks the email address of the user the given email verification token was issued
+// to as verified, and removes the pending verification tokens of the user.
+// If the token is invalid, expired or already used, an error is returned.
+func VerifyEmail(token string) (*db.User, error) {
+	tokenModel, err := VerifyToken(token, db.TokenTypeEmailVerification)
+	if err != nil {
+		return nil, errors.New("invalid or expired verification token")
+	}
+
+	user, err := FindUserById(tokenModel.User)
+	if err != nil {
+		return nil, err
+	}
+
+	user.EmailVarified = true
+	user.UpdatedAt = time.Now()
+	_, err = mgm.Coll(user).UpdateOne(mgm.Ctx(), bson.M{field.ID: user.ID}, bson.M{
+		"$set": bson.M{"email_verified": true, "updated_at": user.UpdatedAt},
+	})
+	if err != nil {
+		return nil, errors.New("cannot verify email")
+	}
+	_, _ = mgm.Coll(&db.Token{}).DeleteMany(mgm.Ctx(), bson.M{"user": user.ID, "type": db.TokenTypeEmailVerification})
+
+	return user, nil
+}
+
+// IsEmailVerificationRequired reports whether unverified users are refused in the given
+// EMAIL_VERIFICATION_REQUIRED mode.
+func IsEmailVerificationRequired(mode string) bool {
+	return Config.EmailVerificationRequired == mode
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	db "health/models/db"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateHoliday creates a new clinic-wide holiday on the given "2006-01-02" formatted date.
+// If there is already a holiday on that date, an error is returned.
+func CreateHoliday(date string, name string) (*db.Holiday, error) {
+	holiday := db.NewHoliday(date, name)
+	err := mgm.Coll(holiday).Create(holiday)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, errors.New("there is already a holiday on this date")
+	}
+	if err != nil {
+		return nil, errors.New("cannot create holiday")
+	}
+
+	return holiday, nil
+}
+
+// GetHolidays retrieves the holidays between the given "2006-01-02" formatted dates, both inclusive,
+// sorted by date. Empty bounds are left open.
+func GetHolidays(from string, to string) ([]db.Holiday, error) {
+	filter := bson.M{}
+	dateFilter := bson.M{}
+	if from != "" {
+		dateFilter["$gte"] = from
+	}
+	if to != "" {
+		dateFilter["$lte"] = to
+	}
+	if len(dateFilter) > 0 {
+		filter["date"] = dateFilter
+	}
+
+	holidays := []db.Holiday{}
+	err := mgm.Coll(&db.Holiday{}).SimpleFind(&holidays, filter, options.Find().SetSort(bson.M{"date": 1}))
+	if err != nil {
+		return nil, errors.New("cannot find holidays")
+	}
+	return holidays, nil
+}
+
+// UpdateHoliday moves the holiday with the given id to the given date and renames it.
+// If the holiday does not exist or another holiday is on that date, an error is returned.
+func UpdateHoliday(id primitive.ObjectID, date string, name string) (*db.Holiday, error) {
+	holiday := &db.Holiday{}
+	err := mgm.Coll(holiday).FindByID(id, holiday)
+	if err != nil {
+		return nil, errors.New("cannot find holiday")
+	}
+
+	holiday.Date = date
+	holiday.Name = name
+	err = mgm.Coll(holiday).Update(holiday)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, errors.New("there is already a holiday on this date")
+	}
+	if err != nil {
+		return nil, errors.New("cannot update holiday")
+	}
+
+	return holiday, nil
+}
+
+// DeleteHoliday deletes the holiday with the given id.
+// If the holiday does not exist or the deletion fails, an error is returned.
+func DeleteHoliday(id primitive.ObjectID) error {
+	result, err := mgm.Coll(&db.Holiday{}).DeleteOne(mgm.Ctx(), bson.M{field.ID: id})
+	if err != nil || result.DeletedCount <= 0 {
+		return errors.New("cannot delete holiday")
+	}
+
+	return nil
+}
+
+// holidayIntervals returns the holidays that overlap the interval as whole local days in the
+// timezone of the doctor.
+func holidayIntervals(doctor *db.Doctor, interval Interval) ([]Interval, error) {
+	location := doctor.Schedule.Location()
+	local := interval.Start.In(location)
+	days := map[string]Interval{}
+	dates := []string{}
+	for i := 0; ; i++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+i, 0, 0, 0, 0, location)
+		if !day.Before(interval.End) {
+			break
+		}
+		date := day.Format(db.HolidayDateLayout)
+		days[date] = Interval{Start: day.UTC(), End: day.AddDate(0, 0, 1).UTC()}
+		dates = append(dates, date)
+	}
+
+	holidays := []db.Holiday{}
+	err := mgm.Coll(&db.Holiday{}).SimpleFind(&holidays, bson.M{"date": bson.M{"$in": dates}})
+	if err != nil {
+		return nil, errors.New("cannot find holidays")
+	}
+
+	intervals := make([]Interval, 0, len(holidays))
+	for _, holiday := range holidays {
+		intervals = append(intervals, days[holiday.Date])
+	}
+	return intervals, nil
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	db "health/models/db"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// pendingInvitationFilter matches the invitations that have been neither accepted nor revoked.
+var pendingInvitationFilter = bson.M{"accepted_at": bson.M{"$exists": false}, "revoked_at": bson.M{"$exists": false}}
+
+// CreateInvitation invites the given email address to create an account with the given name and role,
+// on behalf of the admin with the given ObjectID, and emails the invitation token.
+// An invitation of a doctor can link the account to the doctor profile with the given ObjectID, if it is set.
+// The address must not belong to a user or have a pending invitation, and the doctor profile must not
+// be linked to a user or have a pending invitation.
+// If the invitation cannot be saved or sent, an error is returned, and no invitation is left pending.
+func CreateInvitation(actorId primitive.ObjectID, email string, name string, role string, doctorId primitive.ObjectID) (*db.Invitation, error) {
+	if err := CheckUserMail(email); err != nil {
+		return nil, err
+	}
+	if count, err := mgm.Coll(&db.Invitation{}).CountDocuments(mgm.Ctx(), withPendingInvitation(bson.M{"email": email})); err != nil || count > 0 {
+		return nil, errors.New("email already has a pending invitation")
+	}
+
+	invitation := db.NewInvitation(email, name, role, actorId)
+	if !doctorId.IsZero() {
+		if role != db.RoleDoctor {
+			return nil, errors.New("only doctors can be linked to a doctor profile")
+		}
+		doctor, err := FindDoctorById(doctorId)
+		if err != nil {
+			return nil, err
+		}
+		if !doctor.User.IsZero() {
+			return nil, errors.New("doctor is already linked to a user")
+		}
+		if count, err := mgm.Coll(invitation).CountDocuments(mgm.Ctx(), withPendingInvitation(bson.M{"doctor": doctorId})); err != nil || count > 0 {
+			return nil, errors.New("doctor already has a pending invitation")
+		}
+		invitation.Doctor = doctorId
+	}
+
+	if err := mgm.Coll(invitation).Create(invitation); err != nil {
+		return nil, errors.New("cannot create invitation")
+	}
+	if err := sendInvitation(invitation); err != nil {
+		// an invitation that was never received would block new invitations of the address
+		if err := mgm.Coll(invitation).Delete(invitation); err != nil {
+			log.Printf("Cannot delete unsent invitation %s: %v", invitation.ID.Hex(), err)
+		}
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// withPendingInvitation adds the conditions of a pending invitation to the given filter.
+func withPendingInvitation(filter bson.M) bson.M {
+	for key, value := range pendingInvitationFilter {
+		filter[key] = value
+	}
+
+	return filter
+}
+
+// sendInvitation signs a new token for the invitation, which replaces the tokens sent before and
+// expires after INVITATION_EXPIRATION_HOURS, saves the invitation and emails the token.
+func sendInvitation(invitation *db.Invitation) error {
+	invitation.TokenID = primitive.NewObjectID().Hex()
+	invitation.SentAt = time.Now().UTC()
+	invitation.ExpiresAt = invitation.SentAt.Add(time.Duration(Config.InvitationHours) * time.Hour)
+
+	token, err := signJWT(&db.UserClaims{
+		Email: invitation.Email,
+		Type:  db.TokenTypeInvitation,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        invitation.TokenID,
+			IssuedAt:  jwt.NewNumericDate(invitation.SentAt),
+			ExpiresAt: jwt.NewNumericDate(invitation.ExpiresAt),
+			Subject:   invitation.ID.Hex(),
+		},
+	})
+	if err != nil {
+		return errors.New("cannot create invitation token")
+	}
+
+	if err := mgm.Coll(invitation).Update(invitation); err != nil {
+		return errors.New("cannot update invitation")
+	}
+
+	return SendMail(Mail{
+		To:      invitation.Email,
+		Subject: "You have been invited to Health API",
+		Body: fmt.Sprintf("Hello %s,\n\nYou have been invited to create an account with the role %q. Use the token below to choose your password and create it:\n\n%s\n\nThe token expires in %d hour(s).\n",
+			invitation.Name, invitation.Role, token, Config.InvitationHours),
+	})
+}
+
+// GetPendingInvitations retrieves the invitations that have been neither accepted nor revoked, newest first.
+// Expired invitations are included, so that they can be resent.
+// If the invitations cannot be retrieved, an error is returned.
+func GetPendingInvitations() ([]db.Invitation, error) {
+	invitations := []db.Invitation{}
+	err := mgm.Coll(&db.Invitation{}).SimpleFind(&invitations, withPendingInvitation(bson.M{}),
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, errors.New("cannot find invitations")
+	}
+
+	return invitations, nil
+}
+
+// findPendingInvitation retrieves the pending invitation with the given ObjectID.
+// If no such invitation exists, an error is returned.
+func findPendingInvitation(invitationId primitive.ObjectID) (*db.Invitation, error) {
+	invitation := &db.Invitation{}
+	if err := mgm.Coll(invitation).First(withPendingInvitation(bson.M{field.ID: invitationId}), invitation); err != nil {
+		return nil, errors.New("cannot find invitation")
+	}
+
+	return invitation, nil
+}
+
+// ResendInvitation emails a new token for the pending invitation with the given ObjectID, with a new
+// expiry. The tokens sent before stop working.
+// If the invitation does not exist, its address belongs to a user by now, or the invitation cannot be sent, an error is returned.
+func ResendInvitation(invitationId primitive.ObjectID) (*db.Invitation, error) {
+	invitation, err := findPendingInvitation(invitationId)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckUserMail(invitation.Email); err != nil {
+		return nil, err
+	}
+
+	if err := sendInvitation(invitation); err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// RevokeInvitation revokes the pending invitation with the given ObjectID, so that its token stops working.
+// If no such invitation exists, an error is returned.
+func RevokeInvitation(invitationId primitive.ObjectID) error {
+	now := time.Now().UTC()
+	result, err := mgm.Coll(&db.Invitation{}).UpdateOne(mgm.Ctx(), withPendingInvitation(bson.M{field.ID: invitationId}),
+		bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}})
+	if err != nil || result.MatchedCount == 0 {
+		return errors.New("cannot find invitation")
+	}
+
+	return nil
+}
+
+// AcceptInvitation creates the account of the invitation the given token was signed for, with the given
+// password, the invited role and a verified email address, since the token was received there.
+// An invitation of a doctor links the account to the doctor profile, and is refused if the profile has been
+// linked to another user since. The invitation is recorded in the audit log of the user, along with the
+// admin who sent it.
+// If the token is invalid, expired, replaced by a resent one, or its invitation was already accepted or
+// revoked, an error is returned.
+func AcceptInvitation(token string, password string) (*db.User, error) {
+	claims := &db.UserClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, jwtVerificationKey)
+	if err != nil || claims.Type != db.TokenTypeInvitation {
+		return nil, errors.New("invalid or expired invitation")
+	}
+
+	invitationId, _ := primitive.ObjectIDFromHex(claims.Subject)
+	invitation, err := findPendingInvitation(invitationId)
+	if err != nil || invitation.TokenID != claims.ID {
+		return nil, errors.New("invalid or expired invitation")
+	}
+	if err := CheckUserMail(invitation.Email); err != nil {
+		return nil, err
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, errors.New("cannot generate hashed password")
+	}
+
+	// claiming the invitation first makes the token single-use even if two accepts race
+	now := time.Now().UTC()
+	result, err := mgm.Coll(invitation).UpdateOne(mgm.Ctx(), withPendingInvitation(bson.M{field.ID: invitation.ID, "token_id": claims.ID}),
+		bson.M{"$set": bson.M{"accepted_at": now, "updated_at": now}})
+	if err != nil || result.MatchedCount == 0 {
+		return nil, errors.New("invalid or expired invitation")
+	}
+
+	user := db.NewUser(invitation.Email, string(hashed), invitation.Name, invitation.Role)
+	user.ID = primitive.NewObjectID()
+	user.EmailVarified = true
+
+	if !invitation.Doctor.IsZero() {
+		// the profile may have been linked to another user since the invitation was sent
+		result, err := mgm.Coll(&db.Doctor{}).UpdateOne(mgm.Ctx(),
+			bson.M{field.ID: invitation.Doctor, "user": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"user": user.ID, "updated_at": now}})
+		if err != nil || result.MatchedCount == 0 {
+			releaseInvitation(invitation)
+			return nil, errors.New("doctor profile of the invitation is already linked to another user, ask an admin for a new invitation")
+		}
+	}
+
+	if err := mgm.Coll(user).Create(user); err != nil {
+		if !invitation.Doctor.IsZero() {
+			_, _ = mgm.Coll(&db.Doctor{}).UpdateOne(mgm.Ctx(), bson.M{field.ID: invitation.Doctor, "user": user.ID}, bson.M{"$unset": bson.M{"user": ""}})
+		}
+		releaseInvitation(invitation)
+		return nil, errors.New("cannot create new user")
+	}
+
+	_, err = mgm.Coll(invitation).UpdateOne(mgm.Ctx(), bson.M{field.ID: invitation.ID}, bson.M{"$set": bson.M{"user": user.ID}})
+	if err != nil {
+		log.Printf("Cannot record user %s on invitation %s: %v", user.ID.Hex(), invitation.ID.Hex(), err)
+	}
+
+	if err := recordAuditLog(db.NewAuditLog(invitation.InvitedBy, user.ID, db.AuditActionUserInvited, "",
+		"invited as "+invitation.Role+" with invitation "+invitation.ID.Hex())); err != nil {
+		log.Printf("Cannot record invitation %s: %v", invitation.ID.Hex(), err)
+	}
+
+	return user, nil
+}
+
+// releaseInvitation undoes the claim of an invitation whose account could not be created, so that it can be accepted again.
+func releaseInvitation(invitation *db.Invitation) {
+	_, err := mgm.Coll(invitation).UpdateOne(mgm.Ctx(), bson.M{field.ID: invitation.ID}, bson.M{"$unset": bson.M{"accepted_at": ""}})
+	if err != nil {
+		log.Printf("Cannot release invitation %s: %v", invitation.ID.Hex(), err)
+	}
+}
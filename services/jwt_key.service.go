@@ -0,0 +1,230 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// jwtKey is a key of the JWT key set. Retired keys only hold a public key, which keeps the tokens they
+// signed verifiable until they expire.
+type jwtKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Public  crypto.PublicKey
+	Private crypto.Signer
+}
+
+// JWK is the JSON Web Key representation of a public key, as published in the JWKS endpoint.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var (
+	jwtKeys      = map[string]*jwtKey{}
+	jwtActiveKey *jwtKey
+)
+
+// LoadJWTKeys loads the JWT key set from the PEM files in JWT_KEYS_DIR, where the name of each file
+// without its .pem extension is the kid of the key. RSA keys sign with RS256 and P-256 EC keys with
+// ES256. New tokens are signed with the private key JWT_ACTIVE_KID; the other keys, which may be public
+// keys only, are kept to verify the tokens they signed. Without JWT_KEYS_DIR, tokens are signed with
+// HS256 and JWT_SECRET.
+// If a key cannot be loaded or the active key is missing, an error is returned.
+func LoadJWTKeys() error {
+	jwtKeys = map[string]*jwtKey{}
+	jwtActiveKey = nil
+	if Config.JWTKeysDir == "" {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(Config.JWTKeysDir, "*.pem"))
+	if err != nil {
+		return fmt.Errorf("failed to list JWT keys: %w", err)
+	}
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := loadJWTKey(kid, path)
+		if err != nil {
+			return fmt.Errorf("failed to load JWT key %s: %w", kid, err)
+		}
+		jwtKeys[kid] = key
+	}
+
+	active, ok := jwtKeys[Config.JWTActiveKeyID]
+	if !ok || active.Private == nil {
+		return fmt.Errorf("no private JWT key %q in %s", Config.JWTActiveKeyID, Config.JWTKeysDir)
+	}
+	jwtActiveKey = active
+
+	return nil
+}
+
+// loadJWTKey parses the PEM encoded private or public key in the file at the given path.
+func loadJWTKey(kid string, path string) (*jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &jwtKey{ID: kid}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.Private = signer
+		key.Public = signer.Public()
+	} else {
+		key.Public = parsed
+	}
+
+	switch public := key.Public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must have at least 2048 bits")
+		}
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return nil, errors.New("EC keys must use the P-256 curve")
+		}
+		key.Method = jwt.SigningMethodES256
+	default:
+		return nil, errors.New("only RSA and EC keys are supported")
+	}
+
+	return key, nil
+}
+
+// signJWT signs the claims with the active key, or with HS256 and JWT_SECRET when no key set is configured.
+func signJWT(claims jwt.Claims) (string, error) {
+	if jwtActiveKey == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(Config.JWTSecretKey))
+	}
+
+	token := jwt.NewWithClaims(jwtActiveKey.Method, claims)
+	token.Header["kid"] = jwtActiveKey.ID
+	return token.SignedString(jwtActiveKey.Private)
+}
+
+// jwtVerificationKey returns the key that verifies the given token: the key of the set named by its kid
+// header, or JWT_SECRET for HS256 tokens. The algorithm of the token has to match the key, so that
+// a public key can never be used as an HMAC secret.
+func jwtVerificationKey(token *jwt.Token) (interface{}, error) {
+	if token.Method == jwt.SigningMethodHS256 {
+		// HS256 tokens are the ones signed before a key set was configured
+		if Config.JWTSecretKey == "" {
+			return nil, errors.New("HS256 tokens are not accepted")
+		}
+		return []byte(Config.JWTSecretKey), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := jwtKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if token.Method != key.Method {
+		return nil, fmt.Errorf("key %q does not sign %s tokens", kid, token.Method.Alg())
+	}
+
+	return key.Public, nil
+}
+
+// GetJWKS returns the public keys of the JWT key set, ordered by kid, so that other services can verify
+// the tokens on their own.
+func GetJWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range jwtKeys {
+		jwk := JWK{Use: "sig", Alg: key.Method.Alg(), Kid: key.ID}
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case *ecdsa.PublicKey:
+			jwk.Kty = "EC"
+			jwk.Crv = "P-256"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, 32)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, 32)))
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+
+	return jwks
+}
+
+// PublicKey returns the RSA or P-256 EC public key of the JWK.
+// If the key type is not supported or the key is malformed, an error is returned.
+func (jwk JWK) PublicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 {
+			return nil, fmt.Errorf("malformed RSA key %q", jwk.Kid)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q of key %q", jwk.Crv, jwk.Kid)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("malformed EC key %q", jwk.Kid)
+		}
+		public := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !public.Curve.IsOnCurve(public.X, public.Y) {
+			return nil, fmt.Errorf("malformed EC key %q", jwk.Kid)
+		}
+		return public, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q of key %q", jwk.Kty, jwk.Kid)
+}
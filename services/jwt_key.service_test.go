@@ -0,0 +1,65 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+)
+
+func TestJWKPublicKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := base64.RawURLEncoding.EncodeToString
+	n := encode(rsaKey.N.Bytes())
+	e := encode(big.NewInt(int64(rsaKey.E)).Bytes())
+	x := encode(ecKey.X.FillBytes(make([]byte, 32)))
+	y := encode(ecKey.Y.FillBytes(make([]byte, 32)))
+	offCurveY := encode(new(big.Int).Add(ecKey.Y, big.NewInt(1)).FillBytes(make([]byte, 32)))
+
+	tests := []struct {
+		name    string
+		jwk     JWK
+		want    crypto.PublicKey
+		wantErr bool
+	}{
+		{"RSA key", JWK{Kty: "RSA", Kid: "rsa", N: n, E: e}, &rsaKey.PublicKey, false},
+		{"P-256 key", JWK{Kty: "EC", Kid: "ec", Crv: "P-256", X: x, Y: y}, &ecKey.PublicKey, false},
+		{"RSA key without modulus", JWK{Kty: "RSA", Kid: "rsa", E: e}, nil, true},
+		{"RSA key without exponent", JWK{Kty: "RSA", Kid: "rsa", N: n}, nil, true},
+		{"RSA key with padded base64", JWK{Kty: "RSA", Kid: "rsa", N: n + "=", E: e}, nil, true},
+		{"P-384 key", JWK{Kty: "EC", Kid: "ec", Crv: "P-384", X: x, Y: y}, nil, true},
+		{"EC key without curve", JWK{Kty: "EC", Kid: "ec", X: x, Y: y}, nil, true},
+		{"EC key off the curve", JWK{Kty: "EC", Kid: "ec", Crv: "P-256", X: x, Y: offCurveY}, nil, true},
+		{"EC key without coordinates", JWK{Kty: "EC", Kid: "ec", Crv: "P-256"}, nil, true},
+		{"EC key with malformed base64", JWK{Kty: "EC", Kid: "ec", Crv: "P-256", X: "!" + x, Y: y}, nil, true},
+		{"symmetric key", JWK{Kty: "oct", Kid: "oct"}, nil, true},
+		{"missing key type", JWK{Kid: "none", N: n, E: e}, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.jwk.PublicKey()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("PublicKey() error = %v, want error %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if equal, ok := got.(interface{ Equal(crypto.PublicKey) bool }); !ok || !equal.Equal(test.want) {
+				t.Errorf("PublicKey() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	db "health/models/db"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials is returned for a wrong email or password alike, so that a failed login
+// does not tell whether an account exists.
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// dummyPasswordHash is compared against when no user has the given email, so that a login
+// takes as long for unknown addresses as for known ones.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// loginAttemptStore counts failed logins and holds lockouts, keyed by account or IP address.
+type loginAttemptStore interface {
+	// increment adds a failure to the counter of the key and returns the new count.
+	// The counter is dropped once no failure was added for the given window.
+	increment(key string, window time.Duration) (int64, error)
+	// lock locks the key for the given duration.
+	lock(key string, duration time.Duration) error
+	// lockedFor returns how long the key stays locked, or 0 if it is not locked.
+	lockedFor(key string) (time.Duration, error)
+	// reset drops the counter and the lockout of the key.
+	reset(key string) error
+}
+
+var (
+	loginAttemptsOnce sync.Once
+	loginAttempts     loginAttemptStore
+)
+
+// getLoginAttemptStore returns the store of failed logins, in Redis when USE_REDIS is on,
+// so that all instances of the API share it, or in memory otherwise.
+func getLoginAttemptStore() loginAttemptStore {
+	loginAttemptsOnce.Do(func() {
+		if Config.UseRedis {
+			loginAttempts = &redisLoginAttemptStore{client: GetRedisDefaultClient()}
+		} else {
+			loginAttempts = &memoryLoginAttemptStore{entries: map[string]*loginAttemptEntry{}}
+		}
+	})
+
+	return loginAttempts
+}
+
+func accountAttemptKey(email string) string {
+	return "login.attempts.account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipAttemptKey(ip string) string {
+	return "login.attempts.ip:" + ip
+}
+
+// AuthenticateUser returns the user with the given email if the password matches.
+// Otherwise, ErrInvalidCredentials is returned, after the same bcrypt work for unknown emails.
+func AuthenticateUser(email string, password string) (*db.User, error) {
+	user, err := FindUserByEmail(email)
+	if err != nil {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// LoginLockedError is returned when a login step is refused because logins of the account, or from the
+// IP address, are locked out.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return "too many failed login attempts, try again later"
+}
+
+// LoginLockedFor returns how long logins with the given email, or from the given IP address,
+// are locked out, or 0 if they are not. If the store cannot be reached, logins are allowed.
+func LoginLockedFor(email string, ip string) time.Duration {
+	var lockedFor time.Duration
+	for _, key := range []string{accountAttemptKey(email), ipAttemptKey(ip)} {
+		duration, err := getLoginAttemptStore().lockedFor(key)
+		if err != nil {
+			log.Printf("Cannot check login lockout of %s: %v", key, err)
+			continue
+		}
+		if duration > lockedFor {
+			lockedFor = duration
+		}
+	}
+
+	return lockedFor
+}
+
+// RecordLoginFailure counts a failed login for the email and the IP address. Once a counter reaches
+// its limit, the email or IP address is locked out, for LOGIN_LOCKOUT_MINUTES after the first
+// lockout and twice as long after each further failure, up to LOGIN_MAX_LOCKOUT_MINUTES.
+// A lockout of an existing account is recorded as a security event.
+func RecordLoginFailure(email string, ip string, userAgent string) {
+	store := getLoginAttemptStore()
+	window := time.Duration(Config.LoginAttemptWindowMinutes) * time.Minute
+
+	accountFailures, err := store.increment(accountAttemptKey(email), window)
+	if err != nil {
+		log.Printf("Cannot count failed login of %s: %v", email, err)
+	} else if duration := loginLockoutDuration(accountFailures, Config.LoginMaxAttempts); duration > 0 {
+		if err := store.lock(accountAttemptKey(email), duration); err != nil {
+			log.Printf("Cannot lock out %s: %v", email, err)
+		} else if user, err := FindUserByEmail(email); err == nil {
+			RecordSecurityEvent(db.NewSecurityEvent(user.ID, db.SecurityEventAccountLocked, ip, userAgent,
+				fmt.Sprintf("locked for %s after %d failed logins", duration, accountFailures)))
+		}
+	}
+
+	ipFailures, err := store.increment(ipAttemptKey(ip), window)
+	if err != nil {
+		log.Printf("Cannot count failed login from %s: %v", ip, err)
+	} else if duration := loginLockoutDuration(ipFailures, Config.LoginMaxAttemptsPerIP); duration > 0 {
+		if err := store.lock(ipAttemptKey(ip), duration); err != nil {
+			log.Printf("Cannot lock out %s: %v", ip, err)
+		}
+	}
+}
+
+// loginLockoutDuration returns the lockout after the given number of failures, or 0 below the limit.
+func loginLockoutDuration(failures int64, limit int) time.Duration {
+	if failures < int64(limit) {
+		return 0
+	}
+
+	duration := time.Duration(Config.LoginLockoutMinutes) * time.Minute
+	maxDuration := time.Duration(Config.LoginMaxLockoutMinutes) * time.Minute
+	for i := int64(limit); i < failures && duration < maxDuration; i++ {
+		duration *= 2
+	}
+	if duration > maxDuration {
+		duration = maxDuration
+	}
+
+	return duration
+}
+
+// ResetLoginFailures drops the failed login counter and the lockout of the email,
+// after a successful login. The counter of the IP address is kept, so that logging in to one
+// account does not allow guessing the passwords of others.
+func ResetLoginFailures(email string) {
+	if err := getLoginAttemptStore().reset(accountAttemptKey(email)); err != nil {
+		log.Printf("Cannot reset failed logins of %s: %v", email, err)
+	}
+}
+
+// UnlockAccount lifts the lockout of the user and drops their failed login counter.
+// If the store cannot be reached, an error is returned.
+func UnlockAccount(user *db.User) error {
+	if err := getLoginAttemptStore().reset(accountAttemptKey(user.Email)); err != nil {
+		return errors.New("cannot unlock user")
+	}
+
+	return nil
+}
+
+// requestWindow is the window the limits of countRequest apply to.
+const requestWindow = time.Hour
+
+// countRequest counts a request of the given kind, such as "password_reset", against the hourly limits
+// of the email and of the IP address, and reports whether either limit is exceeded.
+// The counters are kept with the failed logins. If they cannot be reached, the request is allowed.
+func countRequest(kind string, email string, ip string, limitPerEmail int, limitPerIP int) bool {
+	limits := []struct {
+		key   string
+		limit int
+	}{
+		{kind + ".requests.account:" + strings.ToLower(strings.TrimSpace(email)), limitPerEmail},
+		{kind + ".requests.ip:" + ip, limitPerIP},
+	}
+
+	limited := false
+	for _, limit := range limits {
+		count, err := getLoginAttemptStore().increment(limit.key, requestWindow)
+		if err != nil {
+			log.Printf("Cannot count request of %s: %v", limit.key, err)
+			continue
+		}
+		if count > int64(limit.limit) {
+			limited = true
+		}
+	}
+
+	return limited
+}
+
+// redisLoginAttemptStore keeps the counters and lockouts as expiring Redis keys.
+type redisLoginAttemptStore struct {
+	client *redis.Client
+}
+
+func (store *redisLoginAttemptStore) increment(key string, window time.Duration) (int64, error) {
+	var count *redis.IntCmd
+	_, err := store.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		count = pipe.Incr(context.Background(), key)
+		pipe.Expire(context.Background(), key, window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count.Val(), nil
+}
+
+func (store *redisLoginAttemptStore) lock(key string, duration time.Duration) error {
+	return store.client.Set(context.Background(), key+":locked", 1, duration).Err()
+}
+
+func (store *redisLoginAttemptStore) lockedFor(key string) (time.Duration, error) {
+	ttl, err := store.client.PTTL(context.Background(), key+":locked").Result()
+	if err != nil {
+		return 0, err
+	}
+	// negative values mean the key does not exist or does not expire
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+func (store *redisLoginAttemptStore) reset(key string) error {
+	return store.client.Del(context.Background(), key, key+":locked").Err()
+}
+
+// loginAttemptEntry is the counter and lockout of one key in memory.
+type loginAttemptEntry struct {
+	failures    int64
+	expiresAt   time.Time
+	lockedUntil time.Time
+}
+
+// memoryLoginAttemptStore keeps the counters and lockouts of a single instance of the API.
+type memoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	entries  map[string]*loginAttemptEntry
+	prunedAt time.Time
+}
+
+func (store *memoryLoginAttemptStore) increment(key string, window time.Duration) (int64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+	store.prune(now)
+	entry, ok := store.entries[key]
+	if !ok || now.After(entry.expiresAt) {
+		entry = &loginAttemptEntry{}
+		store.entries[key] = entry
+	}
+	entry.failures++
+	entry.expiresAt = now.Add(window)
+
+	return entry.failures, nil
+}
+
+func (store *memoryLoginAttemptStore) lock(key string, duration time.Duration) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entry, ok := store.entries[key]
+	if !ok {
+		entry = &loginAttemptEntry{}
+		store.entries[key] = entry
+	}
+	entry.lockedUntil = time.Now().Add(duration)
+	if entry.lockedUntil.After(entry.expiresAt) {
+		entry.expiresAt = entry.lockedUntil
+	}
+
+	return nil
+}
+
+func (store *memoryLoginAttemptStore) lockedFor(key string) (time.Duration, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entry, ok := store.entries[key]
+	if !ok {
+		return 0, nil
+	}
+	if remaining := time.Until(entry.lockedUntil); remaining > 0 {
+		return remaining, nil
+	}
+
+	return 0, nil
+}
+
+func (store *memoryLoginAttemptStore) reset(key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.entries, key)
+	return nil
+}
+
+// prune drops the expired entries, at most once a minute. The caller must hold the lock.
+func (store *memoryLoginAttemptStore) prune(now time.Time) {
+	if now.Sub(store.prunedAt) < time.Minute {
+		return
+	}
+	for key, entry := range store.entries {
+		if now.After(entry.expiresAt) {
+			delete(store.entries, key)
+		}
+	}
+	store.prunedAt = now
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	db "health/models/db"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// MagicLinkDeviceCookie holds the device secret in the browser that requested a magic link.
+	MagicLinkDeviceCookie = "magic_link_device"
+	// MagicLinkDeviceHeader carries the device secret of apps that open magic links themselves.
+	MagicLinkDeviceHeader = "X-Device-Token"
+)
+
+// ErrMagicLinkRateLimited is returned when too many magic links were requested for an email or from an IP address.
+var ErrMagicLinkRateLimited = errors.New("too many magic link requests, try again later")
+
+// IsMagicLinkAllowed reports whether the role of the user is one of the roles an administrator allows to log in with a magic link.
+func IsMagicLinkAllowed(user *db.User) bool {
+	for _, role := range GetSecuritySettings().MagicLinkRoles {
+		if role == user.Role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UpdateMagicLinkRoles stores the roles whose users may log in with a magic link.
+// If the settings cannot be saved, an error is returned.
+func UpdateMagicLinkRoles(roles []string) (*db.SecuritySettings, error) {
+	return updateSecuritySettings("magic_link_roles", roles)
+}
+
+// SendMagicLink emails a single-use login link to the user with the given email address, requested
+// from the given IP address, and returns the secret of the requesting device the link is bound to.
+// Unknown addresses, suspended users and users whose role may not use magic links get no email, but
+// a device secret all the same, so that the response does not reveal which addresses are registered.
+// Requests are counted per email and per IP address, and ErrMagicLinkRateLimited is returned over the limits.
+// If the link cannot be created or sent, the failure is only logged, for the same reason.
+// If the device secret cannot be generated, an error is returned.
+func SendMagicLink(email string, ip string) (string, error) {
+	if err := countMagicLinkRequest(email, ip); err != nil {
+		return "", err
+	}
+
+	deviceSecret, err := randomSecret("")
+	if err != nil {
+		return "", errors.New("cannot generate device secret")
+	}
+
+	user, err := FindUserByEmail(email)
+	if err != nil || user.IsSuspended() || !IsMagicLinkAllowed(user) {
+		return deviceSecret, nil
+	}
+
+	_, err = mgm.Coll(&db.Token{}).DeleteMany(mgm.Ctx(), bson.M{"user": user.ID, "type": db.TokenTypeMagicLink})
+	if err != nil {
+		log.Printf("Cannot replace magic link of user %s: %v", user.ID.Hex(), err)
+		return deviceSecret, nil
+	}
+
+	expiresAt := time.Now().Add(time.Duration(Config.MagicLinkMinutes) * time.Minute)
+	token := db.NewToken(user.ID, "", db.TokenTypeMagicLink, expiresAt)
+	token.Device = hashOAuthSecret(deviceSecret)
+	if err := signAndSaveToken(user, token); err != nil {
+		log.Printf("Cannot create magic link of user %s: %v", user.ID.Hex(), err)
+		return deviceSecret, nil
+	}
+
+	link := Config.AppURL + "/v1/auth/magic-link/verify?token=" + url.QueryEscape(token.Token)
+	err = SendMail(Mail{
+		To:      user.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf("Hello %s,\n\nOpen the link below on the device you asked for it on to log in:\n\n%s\n\nThe link can be used once and expires in %d minute(s). If you did not ask to log in, you can ignore this email.\n",
+			user.Name, link, Config.MagicLinkMinutes),
+	})
+	if err != nil {
+		log.Printf("Cannot send magic link to user %s: %v", user.ID.Hex(), err)
+	}
+
+	return deviceSecret, nil
+}
+
+// countMagicLinkRequest counts a magic link request for the email and the IP address.
+// If either has reached its limit within the last hour, ErrMagicLinkRateLimited is returned.
+func countMagicLinkRequest(email string, ip string) error {
+	if countRequest("magic_link", email, ip, Config.MagicLinkMaxRequests, Config.MagicLinkMaxRequestsPerIP) {
+		return ErrMagicLinkRateLimited
+	}
+
+	return nil
+}
+
+// ExchangeMagicLink consumes the given magic link token presented by the device with the given secret,
+// marks the email address of the user as verified, since the link was received there, and returns the user.
+// If the token is invalid, expired or already used, was requested from another device, or the role of
+// the user may no longer use magic links, an error is returned.
+func ExchangeMagicLink(token string, deviceSecret string) (*db.User, error) {
+	tokenModel, err := VerifyToken(token, db.TokenTypeMagicLink)
+	if err != nil {
+		return nil, errors.New("invalid or expired magic link")
+	}
+	if deviceSecret == "" || subtle.ConstantTimeCompare([]byte(tokenModel.Device), []byte(hashOAuthSecret(deviceSecret))) != 1 {
+		return nil, errors.New("magic link was requested from another device")
+	}
+
+	// consuming the token first makes it single-use even if two exchanges race
+	result, err := mgm.Coll(&db.Token{}).DeleteOne(mgm.Ctx(), bson.M{field.ID: tokenModel.ID})
+	if err != nil || result.DeletedCount == 0 {
+		return nil, errors.New("invalid or expired magic link")
+	}
+
+	user, err := FindUserById(tokenModel.User)
+	if err != nil {
+		return nil, err
+	}
+	if !IsMagicLinkAllowed(user) {
+		return nil, errors.New("magic link login is not available for this account")
+	}
+
+	if !user.EmailVarified {
+		user.EmailVarified = true
+		user.UpdatedAt = time.Now()
+		if err := mgm.Coll(user).Update(user); err != nil {
+			return nil, errors.New("cannot update user")
+		}
+	}
+
+	return user, nil
+}
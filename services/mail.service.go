@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	MailTransportLog  = "log"
+	MailTransportFile = "file"
+	MailTransportSMTP = "smtp"
+)
+
+// Mail is a plain text email.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// MailSender delivers emails. Implementations are selected with MAIL_TRANSPORT.
+type MailSender interface {
+	Send(mail Mail) error
+}
+
+// Mailer is the MailSender used to send emails, set up by InitMailer.
+var Mailer MailSender = LogMailSender{}
+
+// InitMailer sets up Mailer with the transport configured in MAIL_TRANSPORT.
+func InitMailer() {
+	switch Config.MailTransport {
+	case MailTransportFile:
+		Mailer = FileMailSender{Dir: Config.MailFileDir, From: Config.MailFrom}
+	case MailTransportSMTP:
+		Mailer = SMTPMailSender{
+			Addr:     Config.SMTPAddr,
+			Username: Config.SMTPUsername,
+			Password: Config.SMTPPassword,
+			From:     Config.MailFrom,
+		}
+	default:
+		Mailer = LogMailSender{}
+	}
+}
+
+// SendMail sends the email with Mailer.
+func SendMail(mail Mail) error {
+	if err := Mailer.Send(mail); err != nil {
+		log.Printf("Cannot send mail %q to %s: %v", mail.Subject, mail.To, err)
+		return errors.New("cannot send mail")
+	}
+
+	return nil
+}
+
+// LogMailSender writes emails to the application log, for local development.
+type LogMailSender struct{}
+
+// Send logs the email.
+func (sender LogMailSender) Send(mail Mail) error {
+	log.Printf("Mail to %s: %s\n%s", mail.To, mail.Subject, mail.Body)
+	return nil
+}
+
+// FileMailSender writes every email to its own .eml file in Dir, for local development.
+type FileMailSender struct {
+	Dir  string
+	From string
+}
+
+// Send writes the email to a new file in the directory of the sender.
+func (sender FileMailSender) Send(mail Mail) error {
+	if err := os.MkdirAll(sender.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.NewReplacer("@", "_at_", "/", "_").Replace(mail.To))
+	return os.WriteFile(filepath.Join(sender.Dir, name), mailMessage(sender.From, mail), 0o644)
+}
+
+// SMTPMailSender delivers emails through an SMTP server, authenticating with PLAIN auth
+// when a username is set.
+type SMTPMailSender struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers the email through the SMTP server of the sender.
+func (sender SMTPMailSender) Send(mail Mail) error {
+	var auth smtp.Auth
+	if sender.Username != "" {
+		host, _, err := net.SplitHostPort(sender.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", sender.Username, sender.Password, host)
+	}
+	return smtp.SendMail(sender.Addr, auth, sender.From, []string{mail.To}, mailMessage(sender.From, mail))
+}
+
+// mailMessage formats the email as an RFC 5322 message.
+func mailMessage(from string, mail Mail) []byte {
+	var message strings.Builder
+	message.WriteString("From: " + from + "\r\n")
+	message.WriteString("To: " + mail.To + "\r\n")
+	message.WriteString("Subject: " + mail.Subject + "\r\n")
+	message.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(strings.ReplaceAll(mail.Body, "\n", "\r\n"))
+	return []byte(message.String())
+}
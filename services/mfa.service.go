@@ -0,0 +1,357 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	db "health/models/db"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// totpPeriod is the lifetime of a TOTP code, as expected by authenticator apps.
+	totpPeriod = 30 * time.Second
+	// totpSkew is the number of periods a code is still accepted before or after its own,
+	// to allow for clock drift between the server and the device.
+	totpSkew = 1
+	// mfaPendingLifetime is how long the token returned by a password login can be exchanged with a code.
+	mfaPendingLifetime = 5 * time.Minute
+	// mfaPendingAttempts is the number of wrong codes after which an mfa pending token is discarded.
+	mfaPendingAttempts = 5
+	// recoveryCodeCount is the number of recovery codes generated when 2FA is enabled.
+	recoveryCodeCount = 10
+	// securitySettingsRefresh is how long the security settings are cached in memory.
+	securitySettingsRefresh = time.Minute
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPEnrollment is the secret of a TOTP enrollment, to be added to an authenticator app.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// EnrollTOTP generates a new TOTP secret for the user, which replaces any unconfirmed one.
+// The secret takes effect once ConfirmTOTP accepts a first code generated with it.
+// If 2FA is already enabled or the secret cannot be saved, an error is returned.
+func EnrollTOTP(user *db.User) (*TOTPEnrollment, error) {
+	if user.MFA.Enabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, errors.New("cannot generate secret")
+	}
+	secret := totpEncoding.EncodeToString(raw)
+
+	_, err := mgm.Coll(user).UpdateOne(mgm.Ctx(), bson.M{field.ID: user.ID, "mfa.enabled": bson.M{"$ne": true}}, bson.M{
+		"$set": bson.M{"mfa.secret": secret, "updated_at": time.Now()},
+	})
+	if err != nil {
+		return nil, errors.New("cannot save secret")
+	}
+
+	label := url.PathEscape(Config.MFAIssuer + ":" + user.Email)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", Config.MFAIssuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", "6")
+	query.Set("period", "30")
+
+	return &TOTPEnrollment{Secret: secret, URI: "otpauth://totp/" + label + "?" + query.Encode()}, nil
+}
+
+// ConfirmTOTP enables 2FA for the user when the code was generated with the enrolled secret, and returns
+// a new set of recovery codes. The recovery codes are only stored hashed, so they cannot be shown again.
+// If there is no enrollment to confirm or the code is wrong, an error is returned.
+func ConfirmTOTP(user *db.User, code string) ([]string, error) {
+	if user.MFA.Enabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.MFA.Secret == "" {
+		return nil, errors.New("enroll an authenticator first")
+	}
+	step, ok := matchTOTP(user.MFA.Secret, code, 0)
+	if !ok {
+		return nil, errors.New("invalid code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	result, err := mgm.Coll(user).UpdateOne(mgm.Ctx(), bson.M{field.ID: user.ID, "mfa.enabled": bson.M{"$ne": true}, "mfa.secret": user.MFA.Secret}, bson.M{
+		"$set": bson.M{
+			"mfa.enabled":        true,
+			"mfa.enabled_at":     now.UTC(),
+			"mfa.recovery_codes": hashes,
+			"mfa.last_used_step": step,
+			"updated_at":         now,
+		},
+	})
+	if err != nil || result.MatchedCount == 0 {
+		return nil, errors.New("cannot enable two-factor authentication")
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP turns 2FA off for the user after checking their password and a current code or recovery
+// code, and records a security event.
+// If the password or the code is wrong, an error is returned.
+func DisableTOTP(user *db.User, password string, code string, ip string, userAgent string) error {
+	if !user.MFA.Enabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return errors.New("password is incorrect")
+	}
+	if err := CheckMFACode(user, code, ip, userAgent); err != nil {
+		return err
+	}
+
+	_, err := mgm.Coll(user).UpdateOne(mgm.Ctx(), bson.M{field.ID: user.ID}, bson.M{
+		"$set": bson.M{"mfa": db.UserMFA{}, "updated_at": time.Now()},
+	})
+	if err != nil {
+		return errors.New("cannot disable two-factor authentication")
+	}
+	RecordSecurityEvent(db.NewSecurityEvent(user.ID, db.SecurityEventMFADisabled, ip, userAgent, "two-factor authentication was disabled"))
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user after checking a current code.
+// If 2FA is not enabled or the code is wrong, an error is returned.
+func RegenerateRecoveryCodes(user *db.User, code string, ip string, userAgent string) ([]string, error) {
+	if !user.MFA.Enabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+	if err := CheckMFACode(user, code, ip, userAgent); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	_, err = mgm.Coll(user).UpdateOne(mgm.Ctx(), bson.M{field.ID: user.ID}, bson.M{
+		"$set": bson.M{"mfa.recovery_codes": hashes, "updated_at": time.Now()},
+	})
+	if err != nil {
+		return nil, errors.New("cannot save recovery codes")
+	}
+
+	return codes, nil
+}
+
+// CheckMFACode accepts either a TOTP code that has not been used yet or an unused recovery code of the
+// user, which is consumed. Using a recovery code records a security event.
+// If the code is not accepted, an error is returned.
+func CheckMFACode(user *db.User, code string, ip string, userAgent string) error {
+	code = strings.TrimSpace(code)
+	if step, ok := matchTOTP(user.MFA.Secret, code, user.MFA.LastUsedStep); ok {
+		// only one request can move the last used step past this one, so a code works once
+		result, err := mgm.Coll(user).UpdateOne(mgm.Ctx(), bson.M{field.ID: user.ID, "mfa.last_used_step": bson.M{"$lt": step}}, bson.M{
+			"$set": bson.M{"mfa.last_used_step": step},
+		})
+		if err != nil || result.MatchedCount == 0 {
+			return errors.New("invalid code")
+		}
+		return nil
+	}
+
+	result, err := mgm.Coll(user).UpdateOne(mgm.Ctx(), bson.M{field.ID: user.ID, "mfa.recovery_codes": hashRecoveryCode(code)}, bson.M{
+		"$pull": bson.M{"mfa.recovery_codes": hashRecoveryCode(code)},
+	})
+	if err != nil || result.ModifiedCount == 0 {
+		return errors.New("invalid code")
+	}
+	RecordSecurityEvent(db.NewSecurityEvent(user.ID, db.SecurityEventRecoveryCodeUsed, ip, userAgent,
+		fmt.Sprintf("a recovery code was used, %d left", len(user.MFA.RecoveryCodes)-1)))
+
+	return nil
+}
+
+// CreateMFAPendingToken creates the short-lived token a user with 2FA gets from a password login,
+// to be exchanged for access tokens with ExchangeMFAPendingToken.
+func CreateMFAPendingToken(user *db.User) (*db.Token, error) {
+	return CreateToken(user, db.TokenTypeMFAPending, time.Now().Add(mfaPendingLifetime))
+}
+
+// ExchangeMFAPendingToken checks the code of the user the given mfa pending token was issued to and
+// consumes the token, so that access tokens can be issued. The token is discarded after five wrong codes,
+// and every wrong code counts as a failed login of the user from the given IP address, see RecordLoginFailure.
+// While logins of the user or from the IP address are locked out, a *LoginLockedError is returned without checking the code.
+// If the token is invalid or expired, or the code is wrong, an error is returned.
+func ExchangeMFAPendingToken(token string, code string, ip string, userAgent string) (*db.User, error) {
+	tokenModel, err := VerifyToken(token, db.TokenTypeMFAPending)
+	if err != nil {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+	user, err := FindUserById(tokenModel.User)
+	if err != nil {
+		return nil, err
+	}
+	if lockedFor := LoginLockedFor(user.Email, ip); lockedFor > 0 {
+		return nil, &LoginLockedError{RetryAfter: lockedFor}
+	}
+
+	if err := CheckMFACode(user, code, ip, userAgent); err != nil {
+		RecordLoginFailure(user.Email, ip, userAgent)
+		updated := &db.Token{}
+		err := mgm.Coll(tokenModel).FindOneAndUpdate(mgm.Ctx(), bson.M{field.ID: tokenModel.ID}, bson.M{"$inc": bson.M{"attempts": 1}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(updated)
+		if err == nil && updated.Attempts >= mfaPendingAttempts {
+			_ = DeleteTokenById(tokenModel.ID)
+			return nil, errors.New("too many invalid codes, please log in again")
+		}
+		return nil, errors.New("invalid code")
+	}
+
+	if err := DeleteTokenById(tokenModel.ID); err != nil {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+
+	return user, nil
+}
+
+// matchTOTP reports whether the code was generated with the base32 secret for the current time step
+// or one next to it that is after the given last used step, and returns the matching step.
+func matchTOTP(secret string, code string, lastUsedStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) == 0 || len(code) != 6 {
+		return 0, false
+	}
+
+	current := time.Now().Unix() / int64(totpPeriod/time.Second)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// totpCode computes the 6 digit RFC 6238 code of the key for the given time step.
+func totpCode(key []byte, step int64) string {
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", code%1000000)
+}
+
+// generateRecoveryCodes returns new recovery codes along with the hashes to store.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, errors.New("cannot generate recovery codes")
+		}
+		encoded := strings.ToLower(totpEncoding.EncodeToString(raw))
+		codes[i] = encoded[:4] + "-" + encoded[4:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes a recovery code for storage. Recovery codes are random, so a fast hash is enough.
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	securitySettingsMu       sync.RWMutex
+	securitySettingsCached   *db.SecuritySettings
+	securitySettingsLoadedAt time.Time
+)
+
+// GetSecuritySettings returns the security settings, cached in memory for a minute.
+// Without stored settings, the defaults are returned.
+func GetSecuritySettings() *db.SecuritySettings {
+	securitySettingsMu.RLock()
+	cached, loadedAt := securitySettingsCached, securitySettingsLoadedAt
+	securitySettingsMu.RUnlock()
+	if cached != nil && time.Since(loadedAt) < securitySettingsRefresh {
+		return cached
+	}
+
+	settings := &db.SecuritySettings{MFARequiredRoles: []string{}, MagicLinkRoles: []string{}}
+	_ = mgm.Coll(settings).First(bson.M{}, settings)
+
+	securitySettingsMu.Lock()
+	securitySettingsCached, securitySettingsLoadedAt = settings, time.Now()
+	securitySettingsMu.Unlock()
+	return settings
+}
+
+// UpdateMFARequiredRoles stores the roles whose users must enable 2FA.
+// If the settings cannot be saved, an error is returned.
+func UpdateMFARequiredRoles(roles []string) (*db.SecuritySettings, error) {
+	return updateSecuritySettings("mfa_required_roles", roles)
+}
+
+// updateSecuritySettings stores the given value of a setting, by its bson key.
+// If the settings cannot be saved, an error is returned.
+func updateSecuritySettings(key string, value interface{}) (*db.SecuritySettings, error) {
+	_, err := mgm.Coll(&db.SecuritySettings{}).UpdateOne(mgm.Ctx(), bson.M{}, bson.M{
+		"$set":         bson.M{key: value, "updated_at": time.Now().UTC()},
+		"$setOnInsert": bson.M{"created_at": time.Now().UTC()},
+	}, options.Update().SetUpsert(true))
+	if err != nil {
+		return nil, errors.New("cannot update security settings")
+	}
+
+	securitySettingsMu.Lock()
+	securitySettingsCached = nil
+	securitySettingsMu.Unlock()
+	return GetSecuritySettings(), nil
+}
+
+// IsMFARequired reports whether the user has to enable 2FA before using the API, because their role
+// is one of the roles an administrator requires it for.
+func IsMFARequired(user *db.User) bool {
+	if user.MFA.Enabled {
+		return false
+	}
+	for _, role := range GetSecuritySettings().MFARequiredRoles {
+		if role == user.Role {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	models "health/models"
 	db "health/models/db"
@@ -24,23 +25,30 @@ func CreateNote(userId primitive.ObjectID, title string, content string) (*db.No
 	return note, nil
 }
 
-// GetNotes retrieves all notes belonging to a user with the given userId,
-// paginated to the given page and limit.
-// The result is a slice of Note documents.
-// If the page is out of bounds, an error is returned.
-func GetNotes(userId primitive.ObjectID, page int, limit int) ([]db.Note, error) {
-	var notes []db.Note
-	findOptions := options.Find().SetSkip(int64(page * limit)).SetLimit(int64(limit + 1))
-	err := mgm.Coll(&db.Note{}).SimpleFind(
-		&notes,
-		bson.M{"author": userId.Hex()},
-		findOptions,
-	)
+// GetNotes retrieves the notes belonging to a user with the given userId,
+// paginated to the given page and limit, along with the total number of notes the user owns.
+// If the notes cannot be retrieved, an error is returned.
+func GetNotes(ctx context.Context, userId primitive.ObjectID, page int, limit int) ([]db.Note, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	skip := (page - 1) * limit
+	filter := bson.M{"author": userId.Hex()}
+	notes := []db.Note{}
+	opts := options.Find()
+	opts.SetLimit(int64(limit))
+	opts.SetSkip(int64(skip))
+	opts.SetSort(bson.M{"created_at": -1})
+	err := mgm.Coll(&db.Note{}).SimpleFind(&notes, filter, opts)
 
 	if err != nil {
-		return nil, errors.New("cannot find notes")
+		return nil, 0, errors.New("cannot find notes")
 	}
-	return notes, nil
+	total, _ := mgm.Coll(&db.Note{}).CountDocuments(ctx, filter)
+	return notes, total, nil
 }
 
 // GetNoteById retrieves a note from the MongoDB database by the given noteId, only if the user with the given userId is the author.
@@ -56,19 +64,19 @@ func GetNoteById(userId primitive.ObjectID, noteId primitive.ObjectID) (*db.Note
 }
 
 // UpdateNote updates a note with the given noteId, only if the user with the given userId is the author.
-// The note is updated with the given title and content.
+// The note is updated with the given title and content and the updated note is returned.
 // If the note does not exist, an error is returned.
 // If the user is not the author, an error is returned.
 // If the note cannot be updated, an error is returned.
-func UpdateNote(userId primitive.ObjectID, noteId primitive.ObjectID, request *models.NoteRequest) error {
+func UpdateNote(userId primitive.ObjectID, noteId primitive.ObjectID, request *models.NoteRequest) (*db.Note, error) {
 	note := &db.Note{}
 	err := mgm.Coll(note).FindByID(noteId, note)
 	if err != nil {
-		return errors.New("cannot find note")
+		return nil, errors.New("cannot find note")
 	}
 
 	if note.Author != userId.Hex() {
-		return errors.New("you cannot update this note")
+		return nil, errors.New("you cannot update this note")
 	}
 
 	note.Title = request.Title
@@ -76,10 +84,10 @@ func UpdateNote(userId primitive.ObjectID, noteId primitive.ObjectID, request *m
 	err = mgm.Coll(note).Update(note)
 
 	if err != nil {
-		return errors.New("cannot update")
+		return nil, errors.New("cannot update")
 	}
 
-	return nil
+	return note, nil
 }
 
 // DeleteNote deletes a note with the given noteId if the user with the given userId is the author.
@@ -0,0 +1,457 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	db "health/models/db"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// oauthCodeLifetime is how long an authorization code can be exchanged for tokens.
+	oauthCodeLifetime = 5 * time.Minute
+	// oauthSecretPrefix starts every client secret, so that leaked secrets are easy to recognize.
+	oauthSecretPrefix = "hcs_"
+)
+
+// OAuthError is an error of the OAuth endpoints, with one of the error codes of RFC 6749.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Description
+}
+
+func newOAuthError(code string, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// OAuthClientRegistration holds the settings of a new OAuth client.
+type OAuthClientRegistration struct {
+	Name         string
+	Confidential bool
+	RedirectURIs []string
+	GrantTypes   []string
+	Scopes       []string
+	User         primitive.ObjectID
+}
+
+// OAuthAuthorization is an authorization request of a client a user is asked to consent to.
+type OAuthAuthorization struct {
+	Client        *db.OAuthClient
+	RedirectURI   string
+	Scopes        []string
+	State         string
+	CodeChallenge string
+}
+
+// randomSecret returns a random URL-safe string with the given prefix.
+func randomSecret(prefix string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	return prefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// hashOAuthSecret hashes a client secret or an authorization code for storage and lookup.
+// Both are random, so a fast hash is enough.
+func hashOAuthSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateOAuthClient registers a new OAuth client on behalf of the admin with the given ObjectID.
+// Confidential clients get a secret, which is returned but not stored and cannot be retrieved again.
+// A client using the client credentials flow acts as the given user, whose role must grant every scope.
+// If the client cannot be saved, an error is returned.
+func CreateOAuthClient(actorId primitive.ObjectID, registration *OAuthClientRegistration) (*db.OAuthClient, string, error) {
+	client := &db.OAuthClient{
+		Name:         registration.Name,
+		Confidential: registration.Confidential,
+		RedirectURIs: registration.RedirectURIs,
+		GrantTypes:   registration.GrantTypes,
+		Scopes:       registration.Scopes,
+		CreatedBy:    actorId,
+	}
+	if client.RedirectURIs == nil {
+		client.RedirectURIs = []string{}
+	}
+
+	if client.AllowsGrant(db.OAuthGrantClientCredentials) {
+		user, err := FindUserById(registration.User)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, scope := range client.Scopes {
+			if !db.HasPermission(user.Role, scope) {
+				return nil, "", errors.New("scope " + scope + " is not granted to the " + user.Role + " role")
+			}
+		}
+		client.User = user.ID
+	}
+
+	var secret string
+	if client.Confidential {
+		var err error
+		if secret, err = randomSecret(oauthSecretPrefix); err != nil {
+			return nil, "", errors.New("cannot generate client secret")
+		}
+		client.SecretHash = hashOAuthSecret(secret)
+	}
+
+	if err := mgm.Coll(client).Create(client); err != nil {
+		return nil, "", errors.New("cannot create oauth client")
+	}
+
+	return client, secret, nil
+}
+
+// GetOAuthClients retrieves all the OAuth clients, newest first, including revoked ones.
+// If the clients cannot be retrieved, an error is returned.
+func GetOAuthClients() ([]db.OAuthClient, error) {
+	clients := []db.OAuthClient{}
+	err := mgm.Coll(&db.OAuthClient{}).SimpleFind(&clients, bson.M{},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, errors.New("cannot find oauth clients")
+	}
+
+	return clients, nil
+}
+
+// RevokeOAuthClient revokes the OAuth client with the given ObjectID, along with all the sessions
+// and tokens issued to it.
+// If no such active client exists or its tokens cannot be revoked, an error is returned.
+func RevokeOAuthClient(clientId primitive.ObjectID) error {
+	now := time.Now().UTC()
+	result, err := mgm.Coll(&db.OAuthClient{}).UpdateOne(mgm.Ctx(), bson.M{
+		field.ID:     clientId,
+		"revoked_at": bson.M{"$exists": false},
+	}, bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}})
+	if err != nil || result.MatchedCount == 0 {
+		return errors.New("cannot find oauth client")
+	}
+
+	_, err = mgm.Coll(&db.Session{}).UpdateMany(mgm.Ctx(), bson.M{
+		"client":     clientId,
+		"revoked_at": bson.M{"$exists": false},
+	}, bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}})
+	if err != nil {
+		return errors.New("cannot revoke sessions")
+	}
+
+	_, err = mgm.Coll(&db.Token{}).UpdateMany(mgm.Ctx(), bson.M{
+		"client":      clientId,
+		"blacklisted": false,
+	}, bson.M{"$set": bson.M{"blacklisted": true, "updated_at": now}})
+	if err != nil {
+		return errors.New("cannot revoke tokens")
+	}
+
+	return nil
+}
+
+// findOAuthClient retrieves the active OAuth client with the given client_id.
+func findOAuthClient(clientId string) (*db.OAuthClient, error) {
+	id, err := primitive.ObjectIDFromHex(clientId)
+	if err != nil {
+		return nil, errors.New("cannot find oauth client")
+	}
+
+	client := &db.OAuthClient{}
+	if err := mgm.Coll(client).FindByID(id, client); err != nil || client.RevokedAt != nil {
+		return nil, errors.New("cannot find oauth client")
+	}
+
+	return client, nil
+}
+
+// AuthenticateOAuthClient returns the client with the given client_id, after checking the secret of
+// a confidential client. Public clients authenticate with their client_id alone.
+// If the client is unknown, revoked or the secret does not match, an invalid_client error is returned.
+func AuthenticateOAuthClient(clientId string, secret string) (*db.OAuthClient, error) {
+	client, err := findOAuthClient(clientId)
+	if err != nil {
+		return nil, newOAuthError("invalid_client", "client authentication failed")
+	}
+
+	if client.Confidential {
+		if subtle.ConstantTimeCompare([]byte(hashOAuthSecret(secret)), []byte(client.SecretHash)) != 1 {
+			return nil, newOAuthError("invalid_client", "client authentication failed")
+		}
+	} else if secret != "" {
+		return nil, newOAuthError("invalid_client", "public clients have no secret")
+	}
+
+	return client, nil
+}
+
+// PrepareOAuthAuthorization checks an authorization request of the user for the client with the
+// given client_id, and returns what the user is asked to consent to. The redirect URI must be one the
+// client registered, and every requested scope must be allowed for the client and granted by the
+// role of the user.
+// If the request is invalid, an OAuthError is returned.
+func PrepareOAuthAuthorization(user *db.User, clientId string, redirectURI string, scope string, state string, codeChallenge string) (*OAuthAuthorization, error) {
+	client, err := findOAuthClient(clientId)
+	if err != nil {
+		return nil, newOAuthError("invalid_client", "unknown client")
+	}
+	if !client.AllowsRedirectURI(redirectURI) {
+		return nil, newOAuthError("invalid_request", "redirect_uri is not registered for the client")
+	}
+	if !client.AllowsGrant(db.OAuthGrantAuthorizationCode) {
+		return nil, newOAuthError("unauthorized_client", "the client cannot use the authorization code flow")
+	}
+
+	scopes, err := parseOAuthScopes(client, scope)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range scopes {
+		if !db.HasPermission(user.Role, s) {
+			return nil, newOAuthError("invalid_scope", "scope "+s+" is not granted to your account")
+		}
+	}
+
+	return &OAuthAuthorization{
+		Client:        client,
+		RedirectURI:   redirectURI,
+		Scopes:        scopes,
+		State:         state,
+		CodeChallenge: codeChallenge,
+	}, nil
+}
+
+// parseOAuthScopes splits a space separated scope parameter, which must not be empty and only
+// contain scopes allowed for the client.
+func parseOAuthScopes(client *db.OAuthClient, scope string) ([]string, error) {
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		return nil, newOAuthError("invalid_scope", "scope is required")
+	}
+	for _, s := range scopes {
+		if !client.AllowsScope(s) {
+			return nil, newOAuthError("invalid_scope", "scope "+s+" is not allowed for the client")
+		}
+	}
+
+	return scopes, nil
+}
+
+// ApproveOAuthAuthorization issues an authorization code for the consented request of the user,
+// and returns the redirect URI of the client carrying the code and the state.
+// If the code cannot be saved, an error is returned.
+func ApproveOAuthAuthorization(user *db.User, authorization *OAuthAuthorization) (string, error) {
+	code, err := randomSecret("")
+	if err != nil {
+		return "", errors.New("cannot generate authorization code")
+	}
+
+	authorizationCode := &db.OAuthAuthorizationCode{
+		Client:        authorization.Client.ID,
+		User:          user.ID,
+		CodeHash:      hashOAuthSecret(code),
+		RedirectURI:   authorization.RedirectURI,
+		Scopes:        authorization.Scopes,
+		CodeChallenge: authorization.CodeChallenge,
+		ExpiresAt:     time.Now().Add(oauthCodeLifetime).UTC(),
+	}
+	if err := mgm.Coll(authorizationCode).Create(authorizationCode); err != nil {
+		return "", errors.New("cannot save authorization code")
+	}
+
+	return oauthRedirect(authorization.RedirectURI, url.Values{"code": {code}, "state": {authorization.State}}), nil
+}
+
+// DenyOAuthAuthorization returns the redirect URI of the client telling it the user refused access.
+func DenyOAuthAuthorization(authorization *OAuthAuthorization) string {
+	return oauthRedirect(authorization.RedirectURI, url.Values{
+		"error":             {"access_denied"},
+		"error_description": {"the user denied the request"},
+		"state":             {authorization.State},
+	})
+}
+
+// oauthRedirect adds the parameters to the query of the redirect URI, leaving out an empty state.
+func oauthRedirect(redirectURI string, params url.Values) string {
+	if params.Get("state") == "" {
+		params.Del("state")
+	}
+
+	u, _ := url.Parse(redirectURI)
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// ExchangeOAuthAuthorizationCode exchanges an authorization code for a token pair of the user who
+// approved it, in a new session of the client limited to the approved scopes. The redirect URI must
+// match the authorization request and the code verifier its PKCE code challenge, which is checked before
+// the code is used up. A code can be exchanged once: presenting it again with its verifier revokes the
+// session it was exchanged for.
+// If the code is invalid, an invalid_grant OAuthError is returned.
+func ExchangeOAuthAuthorizationCode(client *db.OAuthClient, code string, redirectURI string, codeVerifier string, ip string) (*db.Token, *db.Token, error) {
+	if !client.AllowsGrant(db.OAuthGrantAuthorizationCode) {
+		return nil, nil, newOAuthError("unauthorized_client", "the client cannot use the authorization code flow")
+	}
+
+	authorizationCode := &db.OAuthAuthorizationCode{}
+	err := mgm.Coll(authorizationCode).First(bson.M{"code_hash": hashOAuthSecret(code), "client": client.ID}, authorizationCode)
+	if err != nil || time.Now().After(authorizationCode.ExpiresAt) {
+		return nil, nil, newOAuthError("invalid_grant", "invalid or expired authorization code")
+	}
+	// checked before the code is claimed, so that an intercepted code without its verifier cannot burn it
+	if authorizationCode.RedirectURI != redirectURI {
+		return nil, nil, newOAuthError("invalid_grant", "redirect_uri does not match the authorization request")
+	}
+	if !verifyCodeChallenge(authorizationCode.CodeChallenge, codeVerifier) {
+		return nil, nil, newOAuthError("invalid_grant", "code_verifier does not match the code challenge")
+	}
+
+	// claiming the code atomically lets only one of two racing requests exchange it
+	now := time.Now().UTC()
+	result, err := mgm.Coll(authorizationCode).UpdateOne(mgm.Ctx(), bson.M{
+		field.ID:  authorizationCode.ID,
+		"used_at": bson.M{"$exists": false},
+	}, bson.M{"$set": bson.M{"used_at": now}})
+	if err != nil {
+		return nil, nil, errors.New("cannot exchange authorization code")
+	}
+	if result.MatchedCount == 0 {
+		if !authorizationCode.Session.IsZero() {
+			_ = RevokeSession(authorizationCode.User, authorizationCode.Session)
+		}
+		return nil, nil, newOAuthError("invalid_grant", "authorization code has already been used")
+	}
+
+	user, err := FindUserById(authorizationCode.User)
+	if err != nil || user.IsSuspended() {
+		return nil, nil, newOAuthError("invalid_grant", "the user can no longer be authorized")
+	}
+
+	session := db.NewSession(user.ID, client.Name, ip)
+	session.Client = client.ID
+	session.Scopes = authorizationCode.Scopes
+	accessToken, refreshToken, err := GenerateAccessTokens(user, session)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, _ = mgm.Coll(authorizationCode).UpdateOne(mgm.Ctx(), bson.M{field.ID: authorizationCode.ID},
+		bson.M{"$set": bson.M{"session": session.ID}})
+
+	return accessToken, refreshToken, nil
+}
+
+// verifyCodeChallenge reports whether the PKCE code verifier matches the S256 code challenge.
+func verifyCodeChallenge(codeChallenge string, codeVerifier string) bool {
+	if len(codeVerifier) < 43 || len(codeVerifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(codeVerifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(codeChallenge)) == 1
+}
+
+// RotateOAuthRefreshToken exchanges a refresh token issued to the client for a new token pair,
+// like RotateRefreshToken.
+// If the token is invalid, was not issued to the client or has been used, an invalid_grant OAuthError is returned.
+func RotateOAuthRefreshToken(client *db.OAuthClient, token string, ip string) (*db.Token, *db.Token, error) {
+	if !client.AllowsGrant(db.OAuthGrantRefreshToken) {
+		return nil, nil, newOAuthError("unauthorized_client", "the client cannot use refresh tokens")
+	}
+
+	_, accessToken, refreshToken, err := rotateRefreshToken(token, client.ID, client.Name, ip)
+	if err != nil {
+		return nil, nil, newOAuthError("invalid_grant", err.Error())
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// IssueClientCredentialsToken creates an access token for the client itself, acting as its service
+// account, limited to the requested scopes or to all the scopes of the client when none are requested.
+// If the client cannot use the flow or a scope is not allowed, an OAuthError is returned.
+func IssueClientCredentialsToken(client *db.OAuthClient, scope string) (*db.Token, error) {
+	if !client.AllowsGrant(db.OAuthGrantClientCredentials) || !client.Confidential {
+		return nil, newOAuthError("unauthorized_client", "the client cannot use the client credentials flow")
+	}
+
+	scopes := client.Scopes
+	if strings.TrimSpace(scope) != "" {
+		var err error
+		if scopes, err = parseOAuthScopes(client, scope); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := FindUserById(client.User)
+	if err != nil || user.IsSuspended() {
+		return nil, newOAuthError("invalid_client", "the service account of the client is not available")
+	}
+
+	accessToken := db.NewToken(user.ID, "", db.TokenTypeAccess, time.Now().Add(time.Duration(Config.JWTAccessExpirationMinutes)*time.Minute))
+	accessToken.Client = client.ID
+	accessToken.Scopes = scopes
+	if err := signAndSaveToken(user, accessToken); err != nil {
+		return nil, err
+	}
+
+	return accessToken, nil
+}
+
+// findOAuthToken retrieves the access or refresh token issued to the client, trying the type of the
+// hint first, whether it has been revoked or not.
+func findOAuthToken(client *db.OAuthClient, token string, typeHint string) (*db.Token, error) {
+	types := []string{db.TokenTypeAccess, db.TokenTypeRefresh}
+	if typeHint == "refresh_token" {
+		types = []string{db.TokenTypeRefresh, db.TokenTypeAccess}
+	}
+
+	for _, tokenType := range types {
+		if tokenModel, err := findToken(token, tokenType); err == nil && tokenModel.Client == client.ID {
+			return tokenModel, nil
+		}
+	}
+
+	return nil, errors.New("cannot find token")
+}
+
+// IntrospectOAuthToken returns the token if it is active and was issued to the client, as described
+// by RFC 7662. Clients cannot introspect the tokens of other clients, nor those of first-party logins.
+func IntrospectOAuthToken(client *db.OAuthClient, token string, typeHint string) *db.Token {
+	tokenModel, err := findOAuthToken(client, token, typeHint)
+	if err != nil || tokenModel.BlackListed || tokenModel.RotatedAt != nil || time.Now().After(tokenModel.ExpriesAt) {
+		return nil
+	}
+
+	return tokenModel
+}
+
+// RevokeOAuthToken revokes a token issued to the client, as described by RFC 7009. Like a logout,
+// revoking either token of a pair ends its session, which revokes the other one too. Unknown tokens
+// and tokens of other clients are ignored, so that the client cannot tell them apart.
+func RevokeOAuthToken(client *db.OAuthClient, token string, typeHint string) {
+	if tokenModel, err := findOAuthToken(client, token, typeHint); err == nil {
+		_ = RevokeToken(tokenModel)
+	}
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	// the example of RFC 7636, appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	longVerifier := strings.Repeat("a", 128)
+	longSum := sha256.Sum256([]byte(longVerifier))
+
+	tests := []struct {
+		name      string
+		challenge string
+		verifier  string
+		want      bool
+	}{
+		{"matching verifier", challenge, verifier, true},
+		{"longest verifier", base64.RawURLEncoding.EncodeToString(longSum[:]), longVerifier, true},
+		{"wrong verifier", challenge, strings.Replace(verifier, "d", "e", 1), false},
+		{"plain challenge", verifier, verifier, false},
+		{"padded challenge", challenge + "=", verifier, false},
+		{"missing verifier", challenge, "", false},
+		{"missing challenge", "", verifier, false},
+		{"verifier too short", challenge, verifier[:42], false},
+		{"verifier too long", challenge, strings.Repeat("a", 129), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := verifyCodeChallenge(test.challenge, test.verifier); got != test.want {
+				t.Errorf("verifyCodeChallenge(%q, %q) = %v, want %v", test.challenge, test.verifier, got, test.want)
+			}
+		})
+	}
+}
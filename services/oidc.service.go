@@ -0,0 +1,369 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	db "health/models/db"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// OIDCStateCookie binds a login to the browser that started it, by holding its state until the callback.
+	OIDCStateCookie = "oidc_state"
+	// OIDCLoginLifetime is how long a user has to log in at the provider and come back to the callback.
+	OIDCLoginLifetime = 10 * time.Minute
+	// oidcDiscoveryLifetime is how long the discovered provider configuration is cached.
+	oidcDiscoveryLifetime = time.Hour
+	// oidcKeysRefreshInterval limits how often an unknown key id refetches the provider's JWKS.
+	oidcKeysRefreshInterval = time.Minute
+)
+
+// oidcProviderConfiguration holds the fields of the provider's discovery document that the login uses.
+type oidcProviderConfiguration struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcIDTokenClaims holds the claims of an ID token.
+type oidcIDTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce           string `json:"nonce"`
+	AuthorizedParty string `json:"azp,omitempty"`
+	Email           string `json:"email"`
+	EmailVerified   bool   `json:"email_verified"`
+	Name            string `json:"name"`
+}
+
+var (
+	oidcHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+	oidcMutex             sync.Mutex
+	oidcProvider          *oidcProviderConfiguration
+	oidcProviderFetchedAt time.Time
+	oidcKeys              map[string]crypto.PublicKey
+	oidcKeysFetchedAt     time.Time
+)
+
+// IsOIDCEnabled reports whether OIDC_ISSUER configures an OpenID Connect provider to log in with.
+func IsOIDCEnabled() bool {
+	return Config.OIDCIssuer != ""
+}
+
+// getOIDCProvider returns the configuration of the provider, discovered from its
+// /.well-known/openid-configuration document and cached for an hour. The document is fetched
+// without holding the cache lock, so that a slow provider does not hold up other logins.
+// If the document cannot be fetched or does not belong to OIDC_ISSUER, an error is returned.
+func getOIDCProvider() (*oidcProviderConfiguration, error) {
+	oidcMutex.Lock()
+	provider, fetchedAt := oidcProvider, oidcProviderFetchedAt
+	oidcMutex.Unlock()
+	if provider != nil && time.Since(fetchedAt) < oidcDiscoveryLifetime {
+		return provider, nil
+	}
+
+	provider = &oidcProviderConfiguration{}
+	if err := fetchOIDCDocument(strings.TrimSuffix(Config.OIDCIssuer, "/")+"/.well-known/openid-configuration", provider); err != nil {
+		return nil, err
+	}
+	if provider.Issuer != Config.OIDCIssuer {
+		return nil, fmt.Errorf("identity provider issuer %q does not match OIDC_ISSUER", provider.Issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, errors.New("identity provider configuration is incomplete")
+	}
+
+	oidcMutex.Lock()
+	oidcProvider = provider
+	oidcProviderFetchedAt = time.Now()
+	oidcMutex.Unlock()
+	return provider, nil
+}
+
+// fetchOIDCDocument fetches the JSON document at the given URL of the provider into v.
+func fetchOIDCDocument(documentURL string, v interface{}) error {
+	response, err := oidcHTTPClient.Get(documentURL)
+	if err != nil {
+		return fmt.Errorf("cannot reach identity provider: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("identity provider responded with status %d to %s", response.StatusCode, documentURL)
+	}
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		return fmt.Errorf("cannot decode %s: %w", documentURL, err)
+	}
+
+	return nil
+}
+
+// oidcVerificationKey returns the key of the provider's JWKS that verifies the given ID token, by
+// its kid header. The JWKS is refetched when the kid is unknown, so that rotated keys are picked up,
+// without holding the cache lock during the fetch.
+func oidcVerificationKey(provider *oidcProviderConfiguration) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		oidcMutex.Lock()
+		key, ok := oidcKeys[kid]
+		refresh := !ok && time.Since(oidcKeysFetchedAt) >= oidcKeysRefreshInterval
+		oidcMutex.Unlock()
+
+		if refresh {
+			jwks := &JWKS{}
+			if err := fetchOIDCDocument(provider.JWKSURI, jwks); err != nil {
+				return nil, err
+			}
+			keys := map[string]crypto.PublicKey{}
+			for _, jwk := range jwks.Keys {
+				if jwk.Use != "" && jwk.Use != "sig" {
+					continue
+				}
+				if public, err := jwk.PublicKey(); err == nil {
+					keys[jwk.Kid] = public
+				}
+			}
+
+			oidcMutex.Lock()
+			oidcKeys = keys
+			oidcKeysFetchedAt = time.Now()
+			oidcMutex.Unlock()
+			key, ok = keys[kid]
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown identity provider key %q", kid)
+		}
+
+		// the algorithm of the token has to match the key
+		switch key.(type) {
+		case *rsa.PublicKey:
+			if token.Method != jwt.SigningMethodRS256 {
+				return nil, fmt.Errorf("key %q does not sign %s tokens", kid, token.Method.Alg())
+			}
+		case *ecdsa.PublicKey:
+			if token.Method != jwt.SigningMethodES256 {
+				return nil, fmt.Errorf("key %q does not sign %s tokens", kid, token.Method.Alg())
+			}
+		}
+
+		return key, nil
+	}
+}
+
+// StartOIDCLogin starts a login at the OpenID Connect provider and returns the URL of the provider
+// to send the user to, along with the state the provider hands back to the callback.
+// The login is bound to a nonce and a PKCE code verifier, and expires after 10 minutes.
+// If the provider cannot be discovered or the login cannot be saved, an error is returned.
+func StartOIDCLogin() (string, string, error) {
+	provider, err := getOIDCProvider()
+	if err != nil {
+		return "", "", err
+	}
+
+	state, errState := randomSecret("")
+	nonce, errNonce := randomSecret("")
+	codeVerifier, errVerifier := randomSecret("")
+	if errState != nil || errNonce != nil || errVerifier != nil {
+		return "", "", errors.New("cannot generate login state")
+	}
+
+	loginState := &db.OIDCLoginState{
+		StateHash:    hashOAuthSecret(state),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(OIDCLoginLifetime).UTC(),
+	}
+	if err := mgm.Coll(loginState).Create(loginState); err != nil {
+		return "", "", errors.New("cannot save login state")
+	}
+
+	authorizationURL, err := url.Parse(provider.AuthorizationEndpoint)
+	if err != nil {
+		return "", "", errors.New("identity provider authorization endpoint is invalid")
+	}
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	query := authorizationURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", Config.OIDCClientID)
+	query.Set("redirect_uri", Config.OIDCRedirectURL)
+	query.Set("scope", Config.OIDCScopes)
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	authorizationURL.RawQuery = query.Encode()
+
+	return authorizationURL.String(), state, nil
+}
+
+// CompleteOIDCLogin completes the login with the given state, by exchanging the given authorization
+// code at the provider and validating the ID token it returns against the provider's JWKS.
+// The user linked to the provider account logs in. Otherwise, the provider account is linked to the
+// user with its email address if the provider verified it, or a new user is created with OIDC_DEFAULT_ROLE.
+// The state must match the given state the browser kept in the OIDCStateCookie since the login started,
+// so that a callback URL cannot be completed by another browser.
+// Each state can be used once. If the state is unknown, expired or not bound to the browser, the code is
+// refused, or the ID token is invalid, an error is returned.
+func CompleteOIDCLogin(code string, state string, browserState string) (*db.User, error) {
+	if browserState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return nil, errors.New("login was not started by this browser")
+	}
+
+	loginState := &db.OIDCLoginState{}
+	err := mgm.Coll(loginState).FindOneAndDelete(mgm.Ctx(), bson.M{
+		"state_hash": hashOAuthSecret(state),
+		"expires_at": bson.M{"$gt": time.Now().UTC()},
+	}).Decode(loginState)
+	if err != nil {
+		return nil, errors.New("login state is invalid or expired")
+	}
+
+	provider, err := getOIDCProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := exchangeOIDCCode(provider, code, loginState.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := verifyOIDCIDToken(provider, idToken, loginState.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return linkOIDCUser(claims)
+}
+
+// exchangeOIDCCode exchanges the authorization code at the token endpoint of the provider and returns
+// the ID token. The client authenticates with HTTP Basic when OIDC_CLIENT_SECRET is set.
+func exchangeOIDCCode(provider *oidcProviderConfiguration, code string, codeVerifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {Config.OIDCRedirectURL},
+		"client_id":     {Config.OIDCClientID},
+		"code_verifier": {codeVerifier},
+	}
+	request, err := http.NewRequest(http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", errors.New("identity provider token endpoint is invalid")
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if Config.OIDCClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(Config.OIDCClientID), url.QueryEscape(Config.OIDCClientSecret))
+	}
+
+	response, err := oidcHTTPClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("cannot reach identity provider: %w", err)
+	}
+	defer response.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil || response.StatusCode != http.StatusOK {
+		if body.Error != "" {
+			return "", fmt.Errorf("identity provider refused the code: %s", body.Error)
+		}
+		return "", errors.New("identity provider refused the code")
+	}
+	if body.IDToken == "" {
+		return "", errors.New("identity provider did not return an id token")
+	}
+
+	return body.IDToken, nil
+}
+
+// verifyOIDCIDToken checks the signature of the given ID token against the provider's JWKS, as well
+// as its issuer, audience, expiry and nonce, and returns its claims.
+func verifyOIDCIDToken(provider *oidcProviderConfiguration, idToken string, nonce string) (*oidcIDTokenClaims, error) {
+	claims := &oidcIDTokenClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, oidcVerificationKey(provider),
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}))
+	if err != nil {
+		return nil, errors.New("id token is invalid")
+	}
+
+	if !claims.VerifyIssuer(provider.Issuer, true) || !claims.VerifyAudience(Config.OIDCClientID, true) {
+		return nil, errors.New("id token was not issued to this client")
+	}
+	// a token for several audiences has to be authorized for this client
+	if (len(claims.Audience) > 1 || claims.AuthorizedParty != "") && claims.AuthorizedParty != Config.OIDCClientID {
+		return nil, errors.New("id token was not issued to this client")
+	}
+	if claims.ExpiresAt == nil || claims.Subject == "" {
+		return nil, errors.New("id token is invalid")
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("id token does not belong to this login")
+	}
+
+	return claims, nil
+}
+
+// linkOIDCUser returns the user linked to the provider account of the given ID token claims,
+// links the user with its verified email address, or creates a new one.
+func linkOIDCUser(claims *oidcIDTokenClaims) (*db.User, error) {
+	user := &db.User{}
+	err := mgm.Coll(user).First(bson.M{"oidc.issuer": claims.Issuer, "oidc.subject": claims.Subject}, user)
+	if err == nil {
+		return user, nil
+	}
+
+	// unverified addresses could belong to anyone, so they never link or create accounts
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errors.New("identity provider did not verify the email address")
+	}
+	link := &db.UserOIDC{Issuer: claims.Issuer, Subject: claims.Subject, LinkedAt: time.Now().UTC()}
+
+	user, err = FindUserByEmail(claims.Email)
+	if err == nil {
+		if user.OIDC != nil {
+			return nil, errors.New("email address is linked to another identity provider account")
+		}
+		user.OIDC = link
+		user.EmailVarified = true
+		user.UpdatedAt = time.Now()
+		if err := mgm.Coll(user).Update(user); err != nil {
+			return nil, errors.New("cannot link user")
+		}
+		return user, nil
+	}
+
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = claims.Email
+	}
+	// the user has no password until they set one with the password reset
+	user = db.NewUser(claims.Email, "", name, Config.OIDCDefaultRole)
+	user.EmailVarified = true
+	user.OIDC = link
+	if err := mgm.Coll(user).Create(user); err != nil {
+		return nil, errors.New("cannot create new user")
+	}
+
+	return user, nil
+}
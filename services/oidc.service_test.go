@@ -0,0 +1,161 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"health/models"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestVerifyOIDCIDToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousConfig, previousKeys, previousFetchedAt := Config, oidcKeys, oidcKeysFetchedAt
+	defer func() {
+		Config, oidcKeys, oidcKeysFetchedAt = previousConfig, previousKeys, previousFetchedAt
+	}()
+	Config = &models.EnvConfig{OIDCClientID: "health-api"}
+	// a fresh key set is not refetched, so that unknown kids fail without reaching the JWKS URI
+	oidcKeys = map[string]crypto.PublicKey{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey}
+	oidcKeysFetchedAt = time.Now()
+	provider := &oidcProviderConfiguration{Issuer: "https://idp.example.com", JWKSURI: "http://127.0.0.1:0/jwks"}
+
+	claims := func(changes jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{
+			"iss":   provider.Issuer,
+			"sub":   "idp|123",
+			"aud":   "health-api",
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(5 * time.Minute).Unix(),
+			"nonce": "nonce",
+			"email": "patient@example.com",
+		}
+		for key, value := range changes {
+			if value == nil {
+				delete(claims, key)
+			} else {
+				claims[key] = value
+			}
+		}
+		return claims
+	}
+	sign := func(method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name    string
+		idToken string
+		wantErr bool
+	}{
+		{"RS256 token", sign(jwt.SigningMethodRS256, rsaKey, "rsa", claims(nil)), false},
+		{"ES256 token", sign(jwt.SigningMethodES256, ecKey, "ec", claims(nil)), false},
+		{"audiences authorized for the client", sign(jwt.SigningMethodRS256, rsaKey, "rsa", claims(jwt.MapClaims{"aud": []string{"health-api", "other"}, "azp": "health-api"})), false},
+		{"other issuer", sign(jwt.SigningMethodRS256, rsaKey, "rsa", claims(jwt.MapClaims{"iss": "https://evil.example.com"})), true},
+		{"missing issuer", sign(jwt.SigningMethodRS256, rsaKey, "rsa", claims(jwt.MapClaims{"iss": nil})), true},
+		{"other audience", sign(jwt.SigningMethodRS256, rsaKey, "rsa", claims(jwt.MapClaims{"aud": "other"})), true},
+		{"audiences without azp", sign(jwt.SigningMethodRS256, rsaKey, "rsa", claims(jwt.MapClaims{"aud": []string{"health-api", "other"}})), true},
+		{"audiences authorized for another client", sign(jwt.SigningMethodRS256, rsaKey, "rsa", claims(jwt.MapClaims{"aud": []string{"health-api", "other"}, "azp": "other"})), true},
+		{"azp of another client", sign(jwt.SigningMethodRS256, rsaKey, "rsa", claims(jwt.MapClaims{"azp": "other"})), true},
+		{"other nonce", sign(jwt.SigningMethodRS256, rsaKey, "rsa", claims(jwt.MapClaims{"nonce": "other"})), true},
+		{"missing nonce", sign(jwt.SigningMethodRS256, rsaKey, "rsa", claims(jwt.MapClaims{"nonce": nil})), true},
+		{"expired", sign(jwt.SigningMethodRS256, rsaKey, "rsa", claims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})), true},
+		{"missing expiry", sign(jwt.SigningMethodRS256, rsaKey, "rsa", claims(jwt.MapClaims{"exp": nil})), true},
+		{"missing subject", sign(jwt.SigningMethodRS256, rsaKey, "rsa", claims(jwt.MapClaims{"sub": nil})), true},
+		{"signed by another key", sign(jwt.SigningMethodRS256, otherKey, "rsa", claims(nil)), true},
+		{"unknown kid", sign(jwt.SigningMethodRS256, rsaKey, "unknown", claims(nil)), true},
+		{"RS256 token with an EC kid", sign(jwt.SigningMethodRS256, rsaKey, "ec", claims(nil)), true},
+		{"ES256 token with an RSA kid", sign(jwt.SigningMethodES256, ecKey, "rsa", claims(nil)), true},
+		{"PS256 token with an RSA kid", sign(jwt.SigningMethodPS256, rsaKey, "rsa", claims(nil)), true},
+		{"HS256 token", sign(jwt.SigningMethodHS256, []byte("secret"), "rsa", claims(nil)), true},
+		{"unsigned token", sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "rsa", claims(nil)), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := verifyOIDCIDToken(provider, test.idToken, "nonce")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("verifyOIDCIDToken() error = %v, want error %v", err, test.wantErr)
+			}
+			if err == nil && (got.Subject != "idp|123" || got.Email != "patient@example.com") {
+				t.Errorf("verifyOIDCIDToken() = %+v, want the claims of the token", got)
+			}
+		})
+	}
+}
+
+func TestOIDCVerificationKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousKeys, previousFetchedAt := oidcKeys, oidcKeysFetchedAt
+	defer func() {
+		oidcKeys, oidcKeysFetchedAt = previousKeys, previousFetchedAt
+	}()
+	oidcKeys = map[string]crypto.PublicKey{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey}
+	oidcKeysFetchedAt = time.Now()
+	keyfunc := oidcVerificationKey(&oidcProviderConfiguration{JWKSURI: "http://127.0.0.1:0/jwks"})
+
+	tests := []struct {
+		name    string
+		method  jwt.SigningMethod
+		kid     string
+		want    crypto.PublicKey
+		wantErr bool
+	}{
+		{"RS256 with an RSA key", jwt.SigningMethodRS256, "rsa", &rsaKey.PublicKey, false},
+		{"ES256 with an EC key", jwt.SigningMethodES256, "ec", &ecKey.PublicKey, false},
+		{"ES256 with an RSA key", jwt.SigningMethodES256, "rsa", nil, true},
+		{"RS512 with an RSA key", jwt.SigningMethodRS512, "rsa", nil, true},
+		{"HS256 with an RSA key", jwt.SigningMethodHS256, "rsa", nil, true},
+		{"RS256 with an EC key", jwt.SigningMethodRS256, "ec", nil, true},
+		{"ES384 with an EC key", jwt.SigningMethodES384, "ec", nil, true},
+		{"unknown kid", jwt.SigningMethodRS256, "unknown", nil, true},
+		{"missing kid", jwt.SigningMethodRS256, "", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token := jwt.New(test.method)
+			if test.kid != "" {
+				token.Header["kid"] = test.kid
+			}
+			got, err := keyfunc(token)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("oidcVerificationKey() error = %v, want error %v", err, test.wantErr)
+			}
+			if err == nil && got != test.want {
+				t.Errorf("oidcVerificationKey() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	db "health/models/db"
+	"log"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrPasswordResetRateLimited is returned when too many password resets were requested for an email or from an IP address.
+var ErrPasswordResetRateLimited = errors.New("too many password reset requests, try again later")
+
+// SendPasswordReset replaces the pending password reset tokens of the user with the given email
+// address with a new single-use token that expires after PASSWORD_RESET_EXPIRATION_MINUTES, and
+// mails it to the user. Unknown addresses are ignored, so that the response does not reveal which
+// addresses are registered.
+// Requests, from the given IP address, are counted per email and per IP address, and
+// ErrPasswordResetRateLimited is returned over the limits.
+// If the token cannot be created or the email cannot be sent, the failure is only logged, so that it
+// does not reveal the address either.
+func SendPasswordReset(email string, ip string) error {
+	if countRequest("password_reset", email, ip, Config.PasswordResetMaxRequests, Config.PasswordResetMaxPerIP) {
+		return ErrPasswordResetRateLimited
+	}
+
+	user, err := FindUserByEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	_, err = mgm.Coll(&db.Token{}).DeleteMany(mgm.Ctx(), bson.M{"user": user.ID, "type": db.TokenTypePasswordReset})
+	if err != nil {
+		log.Printf("Cannot replace password reset token of user %s: %v", user.ID.Hex(), err)
+		return nil
+	}
+
+	expiresAt := time.Now().Add(time.Duration(Config.PasswordResetMinutes) * time.Minute)
+	token, err := CreateToken(user, db.TokenTypePasswordReset, expiresAt)
+	if err != nil {
+		log.Printf("Cannot create password reset token of user %s: %v", user.ID.Hex(), err)
+		return nil
+	}
+
+	err = SendMail(Mail{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\nUse the token below to reset your password:\n\n%s\n\nThe token expires in %d minute(s). If you did not ask for a password reset, you can ignore this email.\n",
+			user.Name, token.Token, Config.PasswordResetMinutes),
+	})
+	if err != nil {
+		log.Printf("Cannot send password reset to user %s: %v", user.ID.Hex(), err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets the password of the user the given password reset token was issued to, consumes
+// the token and revokes all the access and refresh tokens of the user.
+// If the token is invalid, expired or already used, an error is returned.
+func ResetPassword(token string, password string) error {
+	tokenModel, err := VerifyToken(token, db.TokenTypePasswordReset)
+	if err != nil {
+		return errors.New("invalid or expired password reset token")
+	}
+
+	// consuming the token first makes it single-use even if two resets race
+	result, err := mgm.Coll(&db.Token{}).DeleteOne(mgm.Ctx(), bson.M{field.ID: tokenModel.ID})
+	if err != nil || result.DeletedCount == 0 {
+		return errors.New("invalid or expired password reset token")
+	}
+
+	return setPassword(tokenModel.User, password)
+}
+
+// ChangePassword sets the password of the user after checking their current password, and revokes
+// all the access and refresh tokens of the user.
+// If the current password does not match or the password cannot be changed, an error is returned.
+func ChangePassword(user *db.User, currentPassword string, password string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		return errors.New("current password is incorrect")
+	}
+
+	return setPassword(user.ID, password)
+}
+
+// setPassword hashes and stores the password of the user with the given ObjectID, then revokes
+// all the access and refresh tokens of the user so that existing sessions have to log in again.
+func setPassword(userId primitive.ObjectID, password string) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("cannot generate hashed password")
+	}
+
+	result, err := mgm.Coll(&db.User{}).UpdateOne(mgm.Ctx(), bson.M{field.ID: userId}, bson.M{
+		"$set": bson.M{"password": string(hashed), "updated_at": time.Now()},
+	})
+	if err != nil || result.MatchedCount == 0 {
+		return errors.New("cannot update password")
+	}
+
+	return RevokeUserTokens(userId)
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	db "health/models/db"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IsResourceOwner reports whether the user owns the resource with the given ID, for the resource
+// named by a scoped permission. Users own their account, sessions and notes, the appointments they
+// booked and, as doctors, their profile and the appointments booked with them.
+// A resource that cannot be found is owned by no one, so that users cannot tell it apart from the
+// resources of others.
+func IsResourceOwner(resource string, resourceId primitive.ObjectID, userId primitive.ObjectID) bool {
+	switch resource {
+	case "users":
+		return resourceId == userId
+	case "sessions":
+		session, err := FindSessionById(resourceId)
+		return err == nil && session.User == userId
+	case "notes":
+		note := &db.Note{}
+		err := mgm.Coll(note).FindByID(resourceId, note)
+		return err == nil && note.Author == userId.Hex()
+	case "doctors":
+		doctor, err := FindDoctorById(resourceId)
+		return err == nil && doctor.User == userId
+	case "appointments":
+		appointment, err := FindAppointmentById(resourceId)
+		if err != nil {
+			return false
+		}
+		if appointment.Patient == userId {
+			return true
+		}
+		doctor, err := FindDoctorByUser(userId)
+		return err == nil && doctor.ID == appointment.Doctor
+	}
+
+	return false
+}
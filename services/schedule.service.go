@@ -0,0 +1,270 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	db "health/models/db"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxSlotsRange is the longest range of time free slots can be requested for at once.
+const MaxSlotsRange = 31 * 24 * time.Hour
+
+// Interval is a half-open [Start, End) range of time.
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Contains reports whether the given interval lies completely inside the interval.
+func (interval Interval) Contains(other Interval) bool {
+	return !other.Start.Before(interval.Start) && !other.End.After(interval.End)
+}
+
+// Overlaps reports whether the given interval shares any time with the interval.
+func (interval Interval) Overlaps(other Interval) bool {
+	return interval.Start.Before(other.End) && other.Start.Before(interval.End)
+}
+
+// WorkingIntervals expands the weekly schedule of the doctor into the concrete working
+// intervals that overlap the range between from and to.
+// The clock times of the schedule are interpreted in its timezone, so intervals follow
+// daylight saving time changes.
+func WorkingIntervals(doctor *db.Doctor, from time.Time, to time.Time) []Interval {
+	intervals := []Interval{}
+	location := doctor.Schedule.Location()
+	window := Interval{Start: from, End: to}
+	local := from.In(location)
+
+	// start a day early so intervals of the previous local day that reach into the range are kept
+	for i := -1; ; i++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+i, 0, 0, 0, 0, location)
+		if !day.Before(to) {
+			break
+		}
+		for _, workInterval := range doctor.Schedule.IntervalsOn(day.Weekday()) {
+			start, err := clockOn(day, workInterval.Start)
+			if err != nil {
+				continue
+			}
+			end, err := clockOn(day, workInterval.End)
+			if err != nil || !start.Before(end) {
+				continue
+			}
+			interval := Interval{Start: start.UTC(), End: end.UTC()}
+			if interval.Overlaps(window) {
+				intervals = append(intervals, interval)
+			}
+		}
+	}
+
+	return intervals
+}
+
+// clockOn returns the time on the given day at the given "15:04" formatted clock time,
+// in the location of the day.
+func clockOn(day time.Time, clock string) (time.Time, error) {
+	parsed, err := time.Parse(db.ClockLayout, clock)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), parsed.Hour(), parsed.Minute(), 0, 0, day.Location()), nil
+}
+
+// onSlotGrid reports whether the time lies on the booking grid of the doctor.
+// The grid repeats every APPOINTMENT_SLOT_MINUTES starting at local midnight in the
+// doctor's timezone, so it lines up with working hours in any UTC offset.
+func onSlotGrid(doctor *db.Doctor, t time.Time) bool {
+	return ceilToSlotGrid(doctor, t).Equal(t)
+}
+
+// ceilToSlotGrid rounds the time up to the next time on the booking grid of the doctor.
+func ceilToSlotGrid(doctor *db.Doctor, t time.Time) time.Time {
+	slotLength := time.Duration(Config.AppointmentSlotMinutes) * time.Minute
+	local := t.In(doctor.Schedule.Location())
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	offset := t.Sub(midnight)
+	if remainder := offset % slotLength; remainder != 0 {
+		return t.Add(slotLength - remainder)
+	}
+	return t
+}
+
+// AvailableIntervals returns the times between from and to the doctor can see patients:
+// the working intervals of the weekly schedule plus extra hours, minus time off and
+// clinic holidays. The intervals are sorted and do not overlap.
+func AvailableIntervals(doctor *db.Doctor, from time.Time, to time.Time) ([]Interval, error) {
+	window := Interval{Start: from, End: to}
+	intervals := WorkingIntervals(doctor, from, to)
+
+	exceptions, err := scheduleExceptionsBetween(doctor.ID, window)
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := holidayIntervals(doctor, window)
+	if err != nil {
+		return nil, err
+	}
+	for _, exception := range exceptions {
+		interval := Interval{Start: exception.StartsAt, End: exception.EndsAt}
+		switch exception.Type {
+		case db.ScheduleExceptionExtraHours:
+			intervals = append(intervals, interval)
+		case db.ScheduleExceptionTimeOff:
+			blocked = append(blocked, interval)
+		}
+	}
+
+	return subtractIntervals(mergeIntervals(intervals), blocked), nil
+}
+
+// IsWithinSchedule reports whether the interval lies completely inside one of the
+// available intervals of the doctor.
+func IsWithinSchedule(doctor *db.Doctor, interval Interval) (bool, error) {
+	available, err := AvailableIntervals(doctor, interval.Start, interval.End)
+	if err != nil {
+		return false, err
+	}
+	for _, working := range available {
+		if working.Contains(interval) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AvailableSlots is the list of free slots of a doctor within a range of time.
+type AvailableSlots struct {
+	DoctorId        primitive.ObjectID `json:"doctor_id"`
+	From            time.Time          `json:"from"`
+	To              time.Time          `json:"to"`
+	DurationMinutes int                `json:"duration_minutes"`
+	Slots           []Interval         `json:"slots"`
+}
+
+// GetAvailableSlots expands the available time of the doctor between from and to into consecutive
+// free slots of the given duration, leaving out the time that is already booked or lies in the past.
+// Slots start on the doctor's booking grid, so each of them can be booked as is.
+// If the range or duration is invalid, an error is returned.
+func GetAvailableSlots(doctor *db.Doctor, from time.Time, to time.Time, duration time.Duration) (*AvailableSlots, error) {
+	slotLength := time.Duration(Config.AppointmentSlotMinutes) * time.Minute
+	if duration < slotLength || duration%slotLength != 0 {
+		return nil, errors.New("duration must be a multiple of the booking slot length")
+	}
+	if !from.Before(to) {
+		return nil, errors.New("from must be before to")
+	}
+	if to.Sub(from) > MaxSlotsRange {
+		return nil, errors.New("range cannot be longer than 31 days")
+	}
+
+	result := &AvailableSlots{
+		DoctorId:        doctor.ID,
+		From:            from.UTC(),
+		To:              to.UTC(),
+		DurationMinutes: int(duration / time.Minute),
+		Slots:           []Interval{},
+	}
+	if !doctor.Availability || !doctor.IsApproved() {
+		return result, nil
+	}
+
+	window := Interval{Start: result.From, End: result.To}
+	if now := time.Now().UTC(); window.Start.Before(now) {
+		window.Start = now
+	}
+	if !window.Start.Before(window.End) {
+		return result, nil
+	}
+
+	booked, err := bookedAppointmentsBetween(doctor.ID, window)
+	if err != nil {
+		return nil, err
+	}
+	busy := make([]Interval, 0, len(booked))
+	for _, appointment := range booked {
+		busy = append(busy, Interval{Start: appointment.StartsAt, End: appointment.EndsAt})
+	}
+
+	available, err := AvailableIntervals(doctor, window.Start, window.End)
+	if err != nil {
+		return nil, err
+	}
+	for _, free := range subtractIntervals(clipIntervals(available, window), busy) {
+		start := ceilToSlotGrid(doctor, free.Start)
+		for end := start.Add(duration); !end.After(free.End); start, end = end, end.Add(duration) {
+			result.Slots = append(result.Slots, Interval{Start: start, End: end})
+		}
+	}
+
+	return result, nil
+}
+
+// clipIntervals returns the parts of the intervals that lie inside the window.
+func clipIntervals(intervals []Interval, window Interval) []Interval {
+	clipped := []Interval{}
+	for _, interval := range intervals {
+		if interval.Start.Before(window.Start) {
+			interval.Start = window.Start
+		}
+		if interval.End.After(window.End) {
+			interval.End = window.End
+		}
+		if interval.Start.Before(interval.End) {
+			clipped = append(clipped, interval)
+		}
+	}
+	return clipped
+}
+
+// mergeIntervals returns the union of the intervals as sorted intervals that do not overlap.
+func mergeIntervals(intervals []Interval) []Interval {
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].Start.Before(intervals[j].Start)
+	})
+
+	merged := []Interval{}
+	for _, interval := range intervals {
+		last := len(merged) - 1
+		if last >= 0 && !interval.Start.After(merged[last].End) {
+			if interval.End.After(merged[last].End) {
+				merged[last].End = interval.End
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
+
+// subtractIntervals returns the parts of the free intervals that do not overlap any of the busy intervals,
+// sorted by their start.
+func subtractIntervals(free []Interval, busy []Interval) []Interval {
+	sort.Slice(busy, func(i, j int) bool {
+		return busy[i].Start.Before(busy[j].Start)
+	})
+
+	result := []Interval{}
+	for _, interval := range free {
+		for _, taken := range busy {
+			if !taken.Overlaps(interval) {
+				continue
+			}
+			if interval.Start.Before(taken.Start) {
+				result = append(result, Interval{Start: interval.Start, End: taken.Start})
+			}
+			interval.Start = taken.End
+		}
+		if interval.Start.Before(interval.End) {
+			result = append(result, interval)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+	return result
+}
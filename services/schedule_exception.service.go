@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	db "health/models/db"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateScheduleException adds time off or extra hours to the schedule of the doctor.
+// Time off is refused while appointments are booked in it, so that they are rescheduled or cancelled first.
+// If the exception cannot be created, an error is returned.
+func CreateScheduleException(doctorId primitive.ObjectID, exceptionType string, startsAt time.Time, endsAt time.Time, reason string, createdBy primitive.ObjectID) (*db.ScheduleException, error) {
+	if exceptionType == db.ScheduleExceptionTimeOff {
+		appointments, err := bookedAppointmentsBetween(doctorId, Interval{Start: startsAt, End: endsAt})
+		if err != nil {
+			return nil, err
+		}
+		if len(appointments) > 0 {
+			return nil, fmt.Errorf("time off overlaps %d booked appointment(s), reschedule or cancel them first", len(appointments))
+		}
+	}
+
+	exception := db.NewScheduleException(doctorId, exceptionType, startsAt.UTC(), endsAt.UTC(), reason, createdBy)
+	err := mgm.Coll(exception).Create(exception)
+	if err != nil {
+		return nil, errors.New("cannot create schedule exception")
+	}
+
+	return exception, nil
+}
+
+// GetScheduleExceptions retrieves the schedule exceptions of the doctor that have not ended yet,
+// soonest first.
+func GetScheduleExceptions(doctorId primitive.ObjectID) ([]db.ScheduleException, error) {
+	exceptions := []db.ScheduleException{}
+	err := mgm.Coll(&db.ScheduleException{}).SimpleFind(&exceptions,
+		bson.M{"doctor": doctorId, "ends_at": bson.M{"$gt": time.Now()}},
+		options.Find().SetSort(bson.M{"starts_at": 1}),
+	)
+	if err != nil {
+		return nil, errors.New("cannot find schedule exceptions")
+	}
+	return exceptions, nil
+}
+
+// DeleteScheduleException deletes the schedule exception with the given id of the doctor.
+// If the exception does not exist or the deletion fails, an error is returned.
+func DeleteScheduleException(doctorId primitive.ObjectID, exceptionId primitive.ObjectID) error {
+	result, err := mgm.Coll(&db.ScheduleException{}).DeleteOne(mgm.Ctx(), bson.M{field.ID: exceptionId, "doctor": doctorId})
+	if err != nil || result.DeletedCount <= 0 {
+		return errors.New("cannot delete schedule exception")
+	}
+
+	return nil
+}
+
+// scheduleExceptionsBetween retrieves the schedule exceptions of the doctor that overlap the interval.
+func scheduleExceptionsBetween(doctorId primitive.ObjectID, interval Interval) ([]db.ScheduleException, error) {
+	exceptions := []db.ScheduleException{}
+	err := mgm.Coll(&db.ScheduleException{}).SimpleFind(&exceptions, bson.M{
+		"doctor":    doctorId,
+		"starts_at": bson.M{"$lt": interval.End},
+		"ends_at":   bson.M{"$gt": interval.Start},
+	})
+	if err != nil {
+		return nil, errors.New("cannot find schedule exceptions")
+	}
+	return exceptions, nil
+}
@@ -0,0 +1,17 @@
+package services
+
+import (
+	db "health/models/db"
+	"log"
+
+	"github.com/kamva/mgm/v3"
+)
+
+// RecordSecurityEvent writes the event to the application log and stores it in the database.
+// Storage failures are only logged, so that recording an event never blocks the response to it.
+func RecordSecurityEvent(event *db.SecurityEvent) {
+	log.Printf("Security event %s for user %s from %s (%s): %s", event.Type, event.User.Hex(), event.IP, event.UserAgent, event.Details)
+	if err := mgm.Coll(event).Create(event); err != nil {
+		log.Printf("Cannot store security event %s for user %s: %v", event.Type, event.User.Hex(), err)
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	db "health/models/db"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// sessionTouchInterval is how stale Session.LastUsedAt may get before a request updates it,
+// so that authenticated requests do not all write to the database.
+const sessionTouchInterval = time.Minute
+
+// FindSessionById retrieves a session from the MongoDB database by the given ObjectID.
+// If the session does not exist, an error is returned.
+func FindSessionById(sessionId primitive.ObjectID) (*db.Session, error) {
+	session := &db.Session{}
+	if err := mgm.Coll(session).FindByID(sessionId, session); err != nil {
+		return nil, errors.New("cannot find session")
+	}
+
+	return session, nil
+}
+
+// GetUserSessions retrieves the active sessions of the user with the given ObjectID,
+// most recently used first.
+// If the sessions cannot be retrieved, an error is returned.
+func GetUserSessions(userId primitive.ObjectID) ([]db.Session, error) {
+	sessions := []db.Session{}
+	err := mgm.Coll(&db.Session{}).SimpleFind(&sessions, bson.M{
+		"user":       userId,
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	}, options.Find().SetSort(bson.D{{Key: "last_used_at", Value: -1}}))
+	if err != nil {
+		return nil, errors.New("cannot find sessions")
+	}
+
+	return sessions, nil
+}
+
+// TouchSession records that the session with the given ObjectID has just been used.
+// The update is skipped when the session was used less than a minute ago.
+func TouchSession(sessionId primitive.ObjectID) {
+	now := time.Now().UTC()
+	_, _ = mgm.Coll(&db.Session{}).UpdateOne(mgm.Ctx(), bson.M{
+		field.ID:       sessionId,
+		"last_used_at": bson.M{"$lt": now.Add(-sessionTouchInterval)},
+	}, bson.M{"$set": bson.M{"last_used_at": now}})
+}
+
+// RevokeSession revokes the session with the given ObjectID of the user with the given ObjectID
+// and blacklists all its tokens.
+// If the user has no such active session or it cannot be revoked, an error is returned.
+func RevokeSession(userId primitive.ObjectID, sessionId primitive.ObjectID) error {
+	result, err := mgm.Coll(&db.Session{}).UpdateOne(mgm.Ctx(), bson.M{
+		field.ID:     sessionId,
+		"user":       userId,
+		"revoked_at": bson.M{"$exists": false},
+	}, bson.M{"$set": bson.M{"revoked_at": time.Now().UTC(), "updated_at": time.Now().UTC()}})
+	if err != nil {
+		return errors.New("cannot revoke session")
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("cannot find session")
+	}
+
+	return revokeSessionTokens(sessionId)
+}
+
+// RevokeToken revokes the session the given token belongs to along with all its tokens.
+// Tokens issued outside of a session are blacklisted on their own.
+// If the token cannot be revoked, an error is returned.
+func RevokeToken(token *db.Token) error {
+	if !token.Session.IsZero() {
+		return RevokeSession(token.User, token.Session)
+	}
+
+	_, err := mgm.Coll(&db.Token{}).UpdateOne(mgm.Ctx(), bson.M{field.ID: token.ID}, bson.M{
+		"$set": bson.M{"blacklisted": true, "updated_at": time.Now().UTC()},
+	})
+	if err != nil {
+		return errors.New("cannot revoke token")
+	}
+
+	return nil
+}
+
+// RevokeUserTokens revokes all the sessions of the user with the given ObjectID and blacklists all
+// their access and refresh tokens, which logs the user out everywhere.
+// If the tokens cannot be blacklisted, an error is returned.
+func RevokeUserTokens(userId primitive.ObjectID) error {
+	_, err := mgm.Coll(&db.Session{}).UpdateMany(mgm.Ctx(), bson.M{
+		"user":       userId,
+		"revoked_at": bson.M{"$exists": false},
+	}, bson.M{"$set": bson.M{"revoked_at": time.Now().UTC(), "updated_at": time.Now().UTC()}})
+	if err != nil {
+		return errors.New("cannot revoke sessions")
+	}
+
+	_, err = mgm.Coll(&db.Token{}).UpdateMany(mgm.Ctx(), bson.M{
+		"user":        userId,
+		"type":        bson.M{"$in": bson.A{db.TokenTypeAccess, db.TokenTypeRefresh}},
+		"blacklisted": false,
+	}, bson.M{
+		"$set": bson.M{"blacklisted": true, "updated_at": time.Now().UTC()},
+	})
+	if err != nil {
+		return errors.New("cannot revoke tokens")
+	}
+
+	return nil
+}
+
+// revokeSessionTokens blacklists all the tokens of the session with the given ObjectID.
+func revokeSessionTokens(sessionId primitive.ObjectID) error {
+	_, err := mgm.Coll(&db.Token{}).UpdateMany(mgm.Ctx(), bson.M{
+		"session":     sessionId,
+		"blacklisted": false,
+	}, bson.M{
+		"$set": bson.M{"blacklisted": true, "updated_at": time.Now().UTC()},
+	})
+	if err != nil {
+		return errors.New("cannot revoke session tokens")
+	}
+
+	return nil
+}
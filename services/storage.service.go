@@ -15,6 +15,7 @@ import (
 	"github.com/kamva/mgm/v3"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -44,6 +45,18 @@ func FreshMongoDB() error {
 	doctorColl := mgm.Coll(&models.Doctor{})
 	noteColl := mgm.Coll(&models.Note{})
 	tokenColl := mgm.Coll(&models.Token{})
+	appointmentColl := mgm.Coll(&models.Appointment{})
+	scheduleExceptionColl := mgm.Coll(&models.ScheduleException{})
+	holidayColl := mgm.Coll(&models.Holiday{})
+	sessionColl := mgm.Coll(&models.Session{})
+	securityEventColl := mgm.Coll(&models.SecurityEvent{})
+	securitySettingsColl := mgm.Coll(&models.SecuritySettings{})
+	auditLogColl := mgm.Coll(&models.AuditLog{})
+	apiKeyColl := mgm.Coll(&models.APIKey{})
+	oauthClientColl := mgm.Coll(&models.OAuthClient{})
+	oauthCodeColl := mgm.Coll(&models.OAuthAuthorizationCode{})
+	oidcStateColl := mgm.Coll(&models.OIDCLoginState{})
+	invitationColl := mgm.Coll(&models.Invitation{})
 
 	collections := []struct {
 		name string
@@ -53,6 +66,18 @@ func FreshMongoDB() error {
 		{"doctors", doctorColl},
 		{"notes", noteColl},
 		{"tokens", tokenColl},
+		{"appointments", appointmentColl},
+		{"schedule_exceptions", scheduleExceptionColl},
+		{"holidays", holidayColl},
+		{"sessions", sessionColl},
+		{"security_events", securityEventColl},
+		{"security_settings", securitySettingsColl},
+		{"audit_logs", auditLogColl},
+		{"api_keys", apiKeyColl},
+		{"oauth_clients", oauthClientColl},
+		{"oauth_authorization_codes", oauthCodeColl},
+		{"oidc_login_states", oidcStateColl},
+		{"invitations", invitationColl},
 	}
 
 	for _, col := range collections {
@@ -78,6 +103,18 @@ func MongoDBStatus() error {
 		{"doctors", mgm.Coll(&models.Doctor{})},
 		{"notes", mgm.Coll(&models.Note{})},
 		{"tokens", mgm.Coll(&models.Token{})},
+		{"appointments", mgm.Coll(&models.Appointment{})},
+		{"schedule_exceptions", mgm.Coll(&models.ScheduleException{})},
+		{"holidays", mgm.Coll(&models.Holiday{})},
+		{"sessions", mgm.Coll(&models.Session{})},
+		{"security_events", mgm.Coll(&models.SecurityEvent{})},
+		{"security_settings", mgm.Coll(&models.SecuritySettings{})},
+		{"audit_logs", mgm.Coll(&models.AuditLog{})},
+		{"api_keys", mgm.Coll(&models.APIKey{})},
+		{"oauth_clients", mgm.Coll(&models.OAuthClient{})},
+		{"oauth_authorization_codes", mgm.Coll(&models.OAuthAuthorizationCode{})},
+		{"oidc_login_states", mgm.Coll(&models.OIDCLoginState{})},
+		{"invitations", mgm.Coll(&models.Invitation{})},
 	}
 
 	fmt.Println("\nMongoDB Collection Status:")
@@ -98,6 +135,120 @@ func MongoDBStatus() error {
 	return nil
 }
 
+// collectionIndexes holds the indexes of the collection that stores the given model.
+type collectionIndexes struct {
+	model   mgm.Model
+	indexes []mongo.IndexModel
+}
+
+// mongoIndexes returns the indexes each collection relies on.
+func mongoIndexes() []collectionIndexes {
+	return []collectionIndexes{
+		{&models.User{}, []mongo.IndexModel{
+			{
+				// an account at an OpenID Connect provider logs in as one user
+				Keys: bson.D{{Key: "oidc.issuer", Value: 1}, {Key: "oidc.subject", Value: 1}},
+				Options: options.Index().
+					SetName("oidc_unique").
+					SetUnique(true).
+					SetPartialFilterExpression(bson.M{"oidc": bson.M{"$exists": true}}),
+			},
+		}},
+		{&models.Doctor{}, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "license", Value: 1}}, Options: options.Index().SetName("license_unique").SetUnique(true)},
+			{
+				// a user account can be linked to at most one doctor profile
+				Keys: bson.D{{Key: "user", Value: 1}},
+				Options: options.Index().
+					SetName("user_unique").
+					SetUnique(true).
+					SetPartialFilterExpression(bson.M{"user": bson.M{"$exists": true}}),
+			},
+			// doctor search filters on the verification status first, then on one of these fields
+			{Keys: bson.D{{Key: "verification.status", Value: 1}, {Key: "search_name", Value: 1}}},
+			{Keys: bson.D{{Key: "verification.status", Value: 1}, {Key: "specialization", Value: 1}, {Key: "search_name", Value: 1}}},
+			{Keys: bson.D{{Key: "verification.status", Value: 1}, {Key: "search_location", Value: 1}}},
+			{Keys: bson.D{{Key: "verification.status", Value: 1}, {Key: "experience_years", Value: -1}}},
+			{Keys: bson.D{{Key: "verification.status", Value: 1}, {Key: "schedule.days.day", Value: 1}}},
+			// doctors near me, documents without coordinates are left out of a 2dsphere index
+			{Keys: bson.D{{Key: "coordinates", Value: "2dsphere"}}},
+		}},
+		{&models.Appointment{}, []mongo.IndexModel{
+			{
+				// A booked slot of a doctor may only be held by one appointment, which
+				// makes concurrent bookings of the same slot fail at insert time.
+				Keys: bson.D{{Key: "doctor", Value: 1}, {Key: "slots", Value: 1}},
+				Options: options.Index().
+					SetName("doctor_booked_slots_unique").
+					SetUnique(true).
+					SetPartialFilterExpression(bson.M{"status": models.AppointmentStatusBooked}),
+			},
+			{Keys: bson.D{{Key: "patient", Value: 1}, {Key: "starts_at", Value: 1}}},
+			{Keys: bson.D{{Key: "doctor", Value: 1}, {Key: "starts_at", Value: 1}}},
+		}},
+		{&models.ScheduleException{}, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "doctor", Value: 1}, {Key: "starts_at", Value: 1}, {Key: "ends_at", Value: 1}}},
+			{Keys: bson.D{{Key: "type", Value: 1}, {Key: "starts_at", Value: 1}, {Key: "ends_at", Value: 1}}},
+		}},
+		{&models.Holiday{}, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "date", Value: 1}}, Options: options.Index().SetName("date_unique").SetUnique(true)},
+		}},
+		{&models.Token{}, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "token", Value: 1}}},
+			{Keys: bson.D{{Key: "user", Value: 1}, {Key: "type", Value: 1}}},
+			{Keys: bson.D{{Key: "session", Value: 1}}},
+			{Keys: bson.D{{Key: "parent", Value: 1}}},
+			{Keys: bson.D{{Key: "client", Value: 1}}, Options: options.Index().SetPartialFilterExpression(bson.M{"client": bson.M{"$exists": true}})},
+			// expired tokens are rejected anyway, revoked ones only need to be kept until they expire
+			{Keys: bson.D{{Key: "expries_at", Value: 1}}, Options: options.Index().SetName("expries_at_ttl").SetExpireAfterSeconds(0)},
+		}},
+		{&models.Session{}, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "user", Value: 1}, {Key: "last_used_at", Value: -1}}},
+			{Keys: bson.D{{Key: "client", Value: 1}}, Options: options.Index().SetPartialFilterExpression(bson.M{"client": bson.M{"$exists": true}})},
+			// a session ends with its last refresh token, so it is removed along with it
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0)},
+		}},
+		{&models.SecurityEvent{}, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "user", Value: 1}, {Key: "created_at", Value: -1}}},
+		}},
+		{&models.AuditLog{}, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "user", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "actor", Value: 1}, {Key: "created_at", Value: -1}}},
+		}},
+		{&models.APIKey{}, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetName("hash_unique").SetUnique(true)},
+			{Keys: bson.D{{Key: "user", Value: 1}, {Key: "created_at", Value: -1}}},
+		}},
+		{&models.OAuthAuthorizationCode{}, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "code_hash", Value: 1}}, Options: options.Index().SetName("code_hash_unique").SetUnique(true)},
+			// codes are single-use and short-lived, used ones are kept until they expire to detect replays
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0)},
+		}},
+		{&models.Invitation{}, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "email", Value: 1}}},
+			{Keys: bson.D{{Key: "doctor", Value: 1}}, Options: options.Index().SetPartialFilterExpression(bson.M{"doctor": bson.M{"$exists": true}})},
+		}},
+		{&models.OIDCLoginState{}, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "state_hash", Value: 1}}, Options: options.Index().SetName("state_hash_unique").SetUnique(true)},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0)},
+		}},
+	}
+}
+
+// CreateMongoIndexes creates the indexes returned by mongoIndexes.
+// Creating an index that already exists is a no-op, so it is safe to call on every startup.
+func CreateMongoIndexes() error {
+	for _, col := range mongoIndexes() {
+		_, err := mgm.Coll(col.model).Indexes().CreateMany(mgm.Ctx(), col.indexes)
+		if err != nil {
+			return fmt.Errorf("failed to create indexes on %s: %w", mgm.CollName(col.model), err)
+		}
+	}
+
+	log.Println("MongoDB indexes are up to date")
+	return nil
+}
+
 var redisDefaultClient *redis.Client
 var redisDefaultOnce sync.Once
 
@@ -172,6 +323,8 @@ func CacheOneNote(userId primitive.ObjectID, note *models.Note) {
 	})
 }
 
+// GetNoteFromCache retrieves a note belonging to a specific user from Redis cache.
+// If UseRedis is disabled or the note is not cached, an error is returned.
 func GetNoteFromCache(userId primitive.ObjectID, noteId primitive.ObjectID) (*models.Note, error) {
 	if !Config.UseRedis {
 		return nil, errors.New("no redis client, set USE_REDIS in .env")
@@ -181,3 +334,14 @@ func GetNoteFromCache(userId primitive.ObjectID, noteId primitive.ObjectID) (*mo
 	err := GetRedisCache().Get(context.TODO(), noteCachekey, note)
 	return note, err
 }
+
+// DeleteNoteFromCache removes a cached note belonging to a specific user, so that
+// subsequent reads fall through to MongoDB.
+// The function does nothing if the UseRedis configuration option is disabled.
+func DeleteNoteFromCache(userId primitive.ObjectID, noteId primitive.ObjectID) {
+	if !Config.UseRedis {
+		return
+	}
+
+	_ = GetRedisCache().Delete(context.TODO(), getNoteCacheKey(userId, noteId))
+}
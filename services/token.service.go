@@ -3,6 +3,7 @@ package services
 import (
 	"errors"
 	db "health/models/db"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -13,33 +14,57 @@ import (
 )
 
 // CreateToken creates a new JWT token for the given user, with the given type and expiration time.
-// The token is signed with the ES256 algorithm and the secret key from the .env file.
+// The token is signed with the active key of the JWT key set, see LoadJWTKeys.
 // The token is then saved to the tokens collection in the database.
 // If the token cannot be created or saved, an error is returned.
 func CreateToken(user *db.User, tokenType string, expiresAt time.Time) (*db.Token, error) {
+	return createToken(user, tokenType, expiresAt, primitive.NilObjectID)
+}
+
+// createToken creates a new JWT token like CreateToken, belonging to the session with the given ObjectID if it is set.
+func createToken(user *db.User, tokenType string, expiresAt time.Time, sessionId primitive.ObjectID) (*db.Token, error) {
+	tokenModel := db.NewToken(user.ID, "", tokenType, expiresAt)
+	tokenModel.Session = sessionId
+	if err := signAndSaveToken(user, tokenModel); err != nil {
+		return nil, err
+	}
+
+	return tokenModel, nil
+}
+
+// signAndSaveToken signs a JWT token for the given user with the type and expiration time of the
+// given token model, sets it on the model and saves the model to the tokens collection.
+// Tokens issued to an OAuth client also carry its client_id and their scope.
+func signAndSaveToken(user *db.User, tokenModel *db.Token) error {
 	claims := &db.UserClaims{
 		Email: user.Email,
-		Type:  tokenType,
+		Type:  tokenModel.Type,
+		Scope: strings.Join(tokenModel.Scopes, " "),
 		RegisteredClaims: jwt.RegisteredClaims{
+			// a unique id keeps tokens issued to the same user within the same second apart
+			ID:        primitive.NewObjectID().Hex(),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			ExpiresAt: jwt.NewNumericDate(tokenModel.ExpriesAt),
 			Subject:   user.ID.Hex(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(Config.JWTSecretKey))
+	if !tokenModel.Client.IsZero() {
+		claims.ClientID = tokenModel.Client.Hex()
+	}
+
+	tokenString, err := signJWT(claims)
 	if err != nil {
-		return nil, errors.New("cannot create access token")
+		return errors.New("cannot create access token")
 	}
 
-	tokenModel := db.NewToken(user.ID, tokenString, tokenType, expiresAt)
+	tokenModel.Token = tokenString
 	err = mgm.Coll(tokenModel).Create(tokenModel)
 	if err != nil {
-		return nil, errors.New("cannot save access token to db")
+		return errors.New("cannot save access token to db")
 	}
 
-	return tokenModel, nil
+	return nil
 }
 
 // DeleteTokenById deletes a token from the database by its ID.
@@ -55,33 +80,184 @@ func DeleteTokenById(id primitive.ObjectID) error {
 	return nil
 }
 
-// GenerateAccessTokens creates a new access and refresh token for the given user.
+// ErrRefreshTokenReused is returned when a refresh token that has already been exchanged is presented again.
+var ErrRefreshTokenReused = errors.New("refresh token has already been used, all tokens of the session are revoked")
+
+// GenerateAccessTokens creates a new access and refresh token for the given user in the given session.
 // The access token has a TTL of JWT_ACCESS_EXPIRATION_MINUTES minutes and the refresh token has a TTL of JWT_REFRESH_EXPIRATION_DAYS days.
-// The tokens are signed with the ES256 algorithm and the secret key from the .env file.
+// The tokens are signed with the active key of the JWT key set, see LoadJWTKeys.
 // The tokens are then saved to the tokens collection in the database.
+// A new session is saved along with its client metadata. An existing session is extended to the new
+// refresh token, and its earlier tokens are revoked since a session holds one token pair at a time.
 // If either token cannot be created or saved, an error is returned.
-func GenerateAccessTokens(user *db.User) (*db.Token, *db.Token, error) {
+func GenerateAccessTokens(user *db.User, session *db.Session) (*db.Token, *db.Token, error) {
+	return generateTokenPair(user, session, primitive.NilObjectID)
+}
+
+// generateTokenPair creates a new token pair like GenerateAccessTokens, where the refresh token records
+// the refresh token with the given ObjectID as its parent if it is set. The tokens of a session
+// authorized for an OAuth client are issued to that client and limited to the scopes of the session.
+func generateTokenPair(user *db.User, session *db.Session, parentId primitive.ObjectID) (*db.Token, *db.Token, error) {
 	accessExpiresAt := time.Now().Add(time.Duration(Config.JWTAccessExpirationMinutes) * time.Minute)
 	refreshExpiresAt := time.Now().Add(time.Duration(Config.JWTRefreshExpirationDays) * time.Hour * 24)
 
-	accessToken, err := CreateToken(user, db.TokenTypeAccess, accessExpiresAt)
-	if err != nil {
+	session.LastUsedAt = time.Now().UTC()
+	session.ExpiresAt = refreshExpiresAt
+	if session.ID.IsZero() {
+		if err := mgm.Coll(session).Create(session); err != nil {
+			return nil, nil, errors.New("cannot create session")
+		}
+	} else {
+		if err := mgm.Coll(session).Update(session); err != nil {
+			return nil, nil, errors.New("cannot update session")
+		}
+		if err := revokeSessionTokens(session.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	accessToken := db.NewToken(user.ID, "", db.TokenTypeAccess, accessExpiresAt)
+	accessToken.Session = session.ID
+	accessToken.Client = session.Client
+	accessToken.Scopes = session.Scopes
+	if err := signAndSaveToken(user, accessToken); err != nil {
 		return nil, nil, err
 	}
 
-	refreshToken, err := CreateToken(user, db.TokenTypeRefresh, refreshExpiresAt)
-	if err != nil {
+	refreshToken := db.NewToken(user.ID, "", db.TokenTypeRefresh, refreshExpiresAt)
+	refreshToken.Session = session.ID
+	refreshToken.Parent = parentId
+	refreshToken.Client = session.Client
+	refreshToken.Scopes = session.Scopes
+	if err := signAndSaveToken(user, refreshToken); err != nil {
 		return nil, nil, err
 	}
 
 	return accessToken, refreshToken, nil
 }
 
+// RotateRefreshToken exchanges the given refresh token for a new token pair in the same session, for the
+// client with the given user agent and IP address. Each refresh token can be exchanged once: when an
+// already exchanged refresh token is presented again, it has been stolen by either the caller or the
+// client that exchanged it first, so the whole token family is revoked, a security event is recorded
+// and ErrRefreshTokenReused is returned.
+// If the token is invalid or revoked, or its session has ended, an error is returned.
+// Refresh tokens issued to OAuth clients can only be rotated by them, see RotateOAuthRefreshToken.
+func RotateRefreshToken(token string, userAgent string, ip string) (*db.User, *db.Token, *db.Token, error) {
+	return rotateRefreshToken(token, primitive.NilObjectID, userAgent, ip)
+}
+
+// rotateRefreshToken rotates the refresh token like RotateRefreshToken, as long as it was issued to the
+// OAuth client with the given ObjectID, or to no client if it is not set.
+func rotateRefreshToken(token string, clientId primitive.ObjectID, userAgent string, ip string) (*db.User, *db.Token, *db.Token, error) {
+	refreshToken, err := findToken(token, db.TokenTypeRefresh)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := checkRefreshToken(refreshToken, clientId); err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			revokeRefreshTokenFamily(refreshToken, userAgent, ip)
+		}
+		return nil, nil, nil, err
+	}
+
+	// claiming the token atomically lets only one of two racing requests exchange it
+	result, err := mgm.Coll(refreshToken).UpdateOne(mgm.Ctx(), bson.M{
+		field.ID:      refreshToken.ID,
+		"rotated_at":  bson.M{"$exists": false},
+		"blacklisted": false,
+	}, bson.M{"$set": bson.M{"rotated_at": time.Now().UTC()}})
+	if err != nil {
+		return nil, nil, nil, errors.New("cannot rotate refresh token")
+	}
+	if result.MatchedCount == 0 {
+		revokeRefreshTokenFamily(refreshToken, userAgent, ip)
+		return nil, nil, nil, ErrRefreshTokenReused
+	}
+
+	user, err := FindUserById(refreshToken.User)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var session *db.Session
+	if refreshToken.Session.IsZero() {
+		// tokens issued before sessions existed start one
+		session = db.NewSession(user.ID, userAgent, ip)
+	} else {
+		session, err = FindSessionById(refreshToken.Session)
+		if err != nil || !session.IsActive() {
+			return nil, nil, nil, errors.New("session has ended")
+		}
+		session.UserAgent = userAgent
+		session.IP = ip
+	}
+
+	accessToken, newRefreshToken, err := generateTokenPair(user, session, refreshToken.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return user, accessToken, newRefreshToken, nil
+}
+
+// checkRefreshToken checks that the refresh token can be rotated by the OAuth client with the given
+// ObjectID, or by no client if it is not set. A token that was already rotated is being reused, so
+// ErrRefreshTokenReused is returned for it, even if it was revoked since.
+func checkRefreshToken(refreshToken *db.Token, clientId primitive.ObjectID) error {
+	if refreshToken.Client != clientId {
+		return errors.New("cannot find token")
+	}
+	if refreshToken.RotatedAt != nil {
+		return ErrRefreshTokenReused
+	}
+	if refreshToken.BlackListed {
+		return errors.New("cannot find token")
+	}
+
+	return nil
+}
+
+// revokeRefreshTokenFamily revokes the session of the given reused refresh token, as well as the session
+// of the refresh token it was rotated to, and records the reuse as a security event.
+func revokeRefreshTokenFamily(refreshToken *db.Token, userAgent string, ip string) {
+	sessions := []primitive.ObjectID{}
+	if !refreshToken.Session.IsZero() {
+		sessions = append(sessions, refreshToken.Session)
+	}
+	child := &db.Token{}
+	if err := mgm.Coll(child).First(bson.M{"parent": refreshToken.ID}, child); err == nil && !child.Session.IsZero() && child.Session != refreshToken.Session {
+		sessions = append(sessions, child.Session)
+	}
+	for _, sessionId := range sessions {
+		_ = RevokeSession(refreshToken.User, sessionId)
+	}
+	_ = RevokeToken(refreshToken)
+
+	RecordSecurityEvent(db.NewSecurityEvent(refreshToken.User, db.SecurityEventRefreshTokenReuse, ip, userAgent,
+		"refresh token "+refreshToken.ID.Hex()+" was presented again after being rotated"))
+}
+
+// VerifyToken checks the signature, type and expiry of the given token, and retrieves it from the database.
+// If the token is invalid, expired, unknown or revoked, an error is returned.
 func VerifyToken(token string, tokenType string) (*db.Token, error) {
+	tokenModel, err := findToken(token, tokenType)
+	if err != nil {
+		return nil, err
+	}
+	if tokenModel.BlackListed {
+		return nil, errors.New("cannot find token")
+	}
+
+	return tokenModel, nil
+}
+
+// findToken checks the signature, type and expiry of the given token, and retrieves it from the
+// database whether it has been revoked or not.
+// If the token is invalid, expired or unknown, an error is returned.
+func findToken(token string, tokenType string) (*db.Token, error) {
 	claims := &db.UserClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(Config.JWTSecretKey), nil
-	})
+	_, err := jwt.ParseWithClaims(token, claims, jwtVerificationKey)
 
 	if err != nil || claims.Type != tokenType {
 		return nil, errors.New("not valid token")
@@ -94,7 +270,7 @@ func VerifyToken(token string, tokenType string) (*db.Token, error) {
 	tokenModel := &db.Token{}
 	userId, _ := primitive.ObjectIDFromHex(claims.Subject)
 	err = mgm.Coll(tokenModel).First(
-		bson.M{"type": tokenType, "user": userId, "blacklisted": false},
+		bson.M{"token": token, "type": tokenType, "user": userId},
 		tokenModel,
 	)
 	if err != nil {
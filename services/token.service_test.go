@@ -0,0 +1,45 @@
+package services
+
+import (
+	"errors"
+	db "health/models/db"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCheckRefreshToken(t *testing.T) {
+	rotatedAt := time.Now()
+	clientId := primitive.NewObjectID()
+
+	tests := []struct {
+		name     string
+		token    db.Token
+		clientId primitive.ObjectID
+		wantErr  bool
+		reused   bool
+	}{
+		{"fresh token", db.Token{}, primitive.NilObjectID, false, false},
+		{"fresh token of the client", db.Token{Client: clientId}, clientId, false, false},
+		{"rotated token", db.Token{RotatedAt: &rotatedAt}, primitive.NilObjectID, true, true},
+		{"rotated and revoked token", db.Token{RotatedAt: &rotatedAt, BlackListed: true}, primitive.NilObjectID, true, true},
+		{"rotated token of the client", db.Token{Client: clientId, RotatedAt: &rotatedAt}, clientId, true, true},
+		{"revoked token", db.Token{BlackListed: true}, primitive.NilObjectID, true, false},
+		{"token of a client rotated without it", db.Token{Client: clientId}, primitive.NilObjectID, true, false},
+		{"rotated token of another client", db.Token{Client: clientId, RotatedAt: &rotatedAt}, primitive.NewObjectID(), true, false},
+		{"token without a client rotated by one", db.Token{}, clientId, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkRefreshToken(&test.token, test.clientId)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("checkRefreshToken() error = %v, want error %v", err, test.wantErr)
+			}
+			if reused := errors.Is(err, ErrRefreshTokenReused); reused != test.reused {
+				t.Errorf("checkRefreshToken() reused = %v, want %v", reused, test.reused)
+			}
+		})
+	}
+}
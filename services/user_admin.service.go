@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	db "health/models/db"
+	"log"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChangeUserRole gives the user a new role on behalf of the admin with the given ObjectID, and records
+// it in the audit log. The role is read on every request, so the change takes effect immediately.
+// If the admin changes their own role, the user already has the role or the role cannot be saved,
+// an error is returned.
+func ChangeUserRole(actorId primitive.ObjectID, user *db.User, role string, reason string) (*db.User, error) {
+	if user.ID == actorId {
+		return nil, errors.New("admins cannot change their own role")
+	}
+	if user.Role == role {
+		return nil, errors.New("user already has this role")
+	}
+
+	entry := db.NewAuditLog(actorId, user.ID, db.AuditActionRoleChanged, reason, fmt.Sprintf("from %s to %s", user.Role, role))
+	if err := recordAuditLog(entry); err != nil {
+		return nil, err
+	}
+
+	// only update the role it was read with, so that concurrent changes are not both applied
+	result, err := mgm.Coll(user).UpdateOne(mgm.Ctx(), bson.M{field.ID: user.ID, "role": user.Role}, bson.M{
+		"$set": bson.M{"role": role, "updated_at": time.Now().UTC()},
+	})
+	if err != nil || result.ModifiedCount == 0 {
+		discardAuditLog(entry)
+		return nil, errors.New("cannot change role")
+	}
+
+	user.Role = role
+	return user, nil
+}
+
+// SuspendUser suspends the user on behalf of the admin with the given ObjectID, logs them out
+// everywhere and records it in the audit log. Suspended users cannot log in nor use their tokens.
+// If the admin suspends themselves, the user is already suspended or cannot be updated, an error is returned.
+func SuspendUser(actorId primitive.ObjectID, user *db.User, reason string) (*db.User, error) {
+	if user.ID == actorId {
+		return nil, errors.New("admins cannot suspend themselves")
+	}
+
+	entry := db.NewAuditLog(actorId, user.ID, db.AuditActionUserSuspended, reason, "")
+	if err := recordAuditLog(entry); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	result, err := mgm.Coll(user).UpdateOne(mgm.Ctx(), bson.M{
+		field.ID:       user.ID,
+		"suspended_at": bson.M{"$exists": false},
+	}, bson.M{"$set": bson.M{"suspended_at": now, "updated_at": now}})
+	if err != nil {
+		discardAuditLog(entry)
+		return nil, errors.New("cannot suspend user")
+	}
+	if result.ModifiedCount == 0 {
+		discardAuditLog(entry)
+		return nil, errors.New("user is already suspended")
+	}
+	user.SuspendedAt = &now
+
+	// the tokens of suspended users are refused anyway, so the suspension stands even if they remain
+	if err := RevokeUserTokens(user.ID); err != nil {
+		log.Printf("Cannot revoke the tokens of suspended user %s: %v", user.ID.Hex(), err)
+	}
+
+	return user, nil
+}
+
+// ReactivateUser lifts the suspension of the user on behalf of the admin with the given ObjectID,
+// and records it in the audit log.
+// If the user is not suspended or cannot be updated, an error is returned.
+func ReactivateUser(actorId primitive.ObjectID, user *db.User, reason string) (*db.User, error) {
+	entry := db.NewAuditLog(actorId, user.ID, db.AuditActionUserReactivated, reason, "")
+	if err := recordAuditLog(entry); err != nil {
+		return nil, err
+	}
+
+	result, err := mgm.Coll(user).UpdateOne(mgm.Ctx(), bson.M{
+		field.ID:       user.ID,
+		"suspended_at": bson.M{"$exists": true},
+	}, bson.M{
+		"$unset": bson.M{"suspended_at": ""},
+		"$set":   bson.M{"updated_at": time.Now().UTC()},
+	})
+	if err != nil {
+		discardAuditLog(entry)
+		return nil, errors.New("cannot reactivate user")
+	}
+	if result.ModifiedCount == 0 {
+		discardAuditLog(entry)
+		return nil, errors.New("user is not suspended")
+	}
+	user.SuspendedAt = nil
+
+	return user, nil
+}
+
+// ForceLogoutUser revokes all the sessions and tokens of the user on behalf of the admin with the
+// given ObjectID, and records it in the audit log.
+// If the tokens cannot be revoked, an error is returned.
+func ForceLogoutUser(actorId primitive.ObjectID, user *db.User, reason string) error {
+	entry := db.NewAuditLog(actorId, user.ID, db.AuditActionUserLoggedOut, reason, "")
+	if err := recordAuditLog(entry); err != nil {
+		return err
+	}
+
+	// tokens may have been revoked before the failure, so the entry is kept
+	return RevokeUserTokens(user.ID)
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"health/models"
+	db "health/models/db"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SubmitDoctorVerification submits the license details and supporting documents of the doctor
+// for review on behalf of the given actor, moving the doctor to the submitted status.
+// If the doctor cannot be submitted from its current status, an error is returned.
+func SubmitDoctorVerification(doctor *db.Doctor, request *models.VerificationSubmissionRequest, actor primitive.ObjectID) error {
+	if other, err := FindDoctorByLicense(request.License); err == nil && other.ID != doctor.ID {
+		return errors.New("license is already in use")
+	}
+
+	return transitionDoctorVerification(doctor, db.VerificationSubmitted, actor, "", bson.M{
+		"license":                         request.License,
+		"verification.issuing_authority":  request.IssuingAuthority,
+		"verification.license_expires_at": request.LicenseExpiresAt.UTC(),
+		"verification.documents":          request.Documents,
+	})
+}
+
+// TransitionDoctorVerification moves the doctor to the given onboarding status on behalf of the given actor.
+// The reason is kept on the verification when the doctor is rejected or suspended.
+// If the move is not allowed from the doctor's current status, an error is returned.
+func TransitionDoctorVerification(doctor *db.Doctor, to string, actor primitive.ObjectID, reason string) error {
+	return transitionDoctorVerification(doctor, to, actor, reason, bson.M{})
+}
+
+// transitionDoctorVerification moves the doctor to the given status, setting the extra fields and
+// recording the move in the verification history in the same update.
+// The update only applies while the doctor is still in the status it was read with, so two
+// concurrent moves cannot both succeed.
+func transitionDoctorVerification(doctor *db.Doctor, to string, actor primitive.ObjectID, reason string, set bson.M) error {
+	from := doctor.Verification.CurrentStatus()
+	if !doctor.Verification.CanTransition(to) {
+		return fmt.Errorf("cannot move doctor from %s to %s", from, to)
+	}
+
+	var fromFilter interface{} = from
+	if from == db.VerificationPending {
+		// doctors stored before onboarding existed have no status yet
+		fromFilter = bson.M{"$in": bson.A{nil, db.VerificationPending}}
+	}
+
+	now := time.Now().UTC()
+	event := db.VerificationEvent{From: from, To: to, Actor: actor, Reason: reason, At: now}
+	set["verification.status"] = to
+	set["verification.reason"] = reason
+	set["updated_at"] = now
+
+	result, err := mgm.Coll(doctor).UpdateOne(mgm.Ctx(),
+		bson.M{field.ID: doctor.ID, "verification.status": fromFilter},
+		bson.M{"$set": set, "$push": bson.M{"verification.history": event}},
+	)
+	if err != nil {
+		return errors.New("cannot update doctor verification")
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("doctor verification changed in the meantime, try again")
+	}
+
+	return mgm.Coll(doctor).FindByID(doctor.ID, doctor)
+}
@@ -59,10 +59,21 @@ func ErrorResponse(c *gin.Context, statusCode int, message string) {
 	response.SendResponse(c)
 }
 
+// DefaultPerPage is the per-page limit of paginated lists when none or an invalid one is requested.
+const DefaultPerPage = 10
+
 // PaginatedSuccessResponse sends a JSON response with the given data, page,
 // per-page limit, and total count, and marks the response as successful.
 // The response will also include the total number of pages.
+// A page below 1 is reported as the first page, and a limit below 1 as DefaultPerPage,
+// like the services clamp them.
 func PaginatedSuccessResponse(c *gin.Context, data interface{}, page int, perPage int, total int64) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = DefaultPerPage
+	}
 	response := &PaginatedResponse{
 		StatusCode:  http.StatusOK,
 		Success:     true,
@@ -70,6 +81,7 @@ func PaginatedSuccessResponse(c *gin.Context, data interface{}, page int, perPag
 		Data:        data,
 		CurrentPage: page,
 		PerPage:     perPage,
+		Total:       total,
 		TotalPages:  (total + int64(perPage) - 1) / int64(perPage),
 	}
 	response.SendPaginatedResponse(c)